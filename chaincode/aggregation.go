@@ -0,0 +1,518 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/gs1"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// Aggregate packs drugs into a case or cases onto a pallet. The container is
+// created on first use and further children can be added to it until it ships.
+func (s *SmartContract) Aggregate(ctx contractapi.TransactionContextInterface, req string) (*model.Container, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	var aggregate dto.Aggregate
+	if err := dto.Decode(req, &aggregate); err != nil {
+		return nil, err
+	}
+
+	container, isNew, err := s.getOrCreateContainer(ctx, org, aggregate.ContainerID, aggregate.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	value := []byte{0x00}
+	for _, drugID := range aggregate.DrugsID {
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get drug: %w", err)
+		}
+		if drug.OwnerID != org.ID {
+			return nil, fmt.Errorf("drug %s does not belong to the caller", drugID)
+		}
+		if drug.IsTransferred {
+			return nil, fmt.Errorf("drug %s is in a pending transfer", drugID)
+		}
+		if drug.IsDispensed {
+			return nil, fmt.Errorf("drug %s has been dispensed", drugID)
+		}
+		if drug.IsDecommissioned {
+			return nil, fmt.Errorf("drug %s has been decommissioned", drugID)
+		}
+		if drug.DecommissionID != "" {
+			return nil, fmt.Errorf("drug %s is awaiting confirmation of decommission %s", drugID, drug.DecommissionID)
+		}
+		if drug.ContainerID != "" {
+			return nil, fmt.Errorf("drug %s is already packed in container %s", drugID, drug.ContainerID)
+		}
+
+		drug.ContainerID = container.ID
+
+		drugJSON, err := json.Marshal(drug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal drug: %w", err)
+		}
+		if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+			return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+		}
+
+		containerDrugIndexKey, err := ctx.GetStub().CreateCompositeKey(containerDrugIndex, []string{container.ID, drug.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %w", err)
+		}
+		if err := ctx.GetStub().PutState(containerDrugIndexKey, value); err != nil {
+			return nil, fmt.Errorf("failed to put container-drug index to world state: %w", err)
+		}
+	}
+
+	for _, childID := range aggregate.ContainersID {
+		child, err := s.GetContainer(ctx, childID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get container: %w", err)
+		}
+		if child.OwnerID != org.ID {
+			return nil, fmt.Errorf("container %s does not belong to the caller", childID)
+		}
+		if child.IsTransferred {
+			return nil, fmt.Errorf("container %s is in a pending transfer", childID)
+		}
+		if child.Level != model.ContainerLevelCase {
+			return nil, fmt.Errorf("container %s is a %s, pallets can only hold cases", childID, child.Level)
+		}
+		if child.ParentID != "" {
+			return nil, fmt.Errorf("container %s is already packed in container %s", childID, child.ParentID)
+		}
+
+		child.ParentID = container.ID
+		if err := s.putContainer(ctx, child); err != nil {
+			return nil, err
+		}
+
+		containerChildIndexKey, err := ctx.GetStub().CreateCompositeKey(containerChildIndex, []string{container.ID, child.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %w", err)
+		}
+		if err := ctx.GetStub().PutState(containerChildIndexKey, value); err != nil {
+			return nil, fmt.Errorf("failed to put container-container index to world state: %w", err)
+		}
+	}
+
+	if err := s.putContainer(ctx, container); err != nil {
+		return nil, err
+	}
+	if isNew {
+		ownerContainerIndexKey, err := ctx.GetStub().CreateCompositeKey(ownerContainerIndex, []string{org.ID, container.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %w", err)
+		}
+		if err := ctx.GetStub().PutState(ownerContainerIndexKey, value); err != nil {
+			return nil, fmt.Errorf("failed to put owner-container index to world state: %w", err)
+		}
+	}
+	log.Printf("Container %s packed: drugs %v, containers %v\n", container.ID, aggregate.DrugsID, aggregate.ContainersID)
+
+	if err := s.emitContainerChanged(ctx, event.TypeContainerAggregated, container, aggregate.ContainersID, aggregate.DrugsID); err != nil {
+		return nil, err
+	}
+
+	return container, nil
+}
+
+// Disaggregate unpacks the direct children of a container and retires its SSCC.
+// Cases on a pallet keep their own contents.
+func (s *SmartContract) Disaggregate(ctx contractapi.TransactionContextInterface, containerID string) (*model.Container, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	if err := gs1.ValidateSSCC(containerID); err != nil {
+		return nil, err
+	}
+
+	container, err := s.GetContainer(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container: %w", err)
+	}
+	if container.OwnerID != org.ID {
+		return nil, fmt.Errorf("container %s does not belong to the caller", containerID)
+	}
+	if container.IsTransferred {
+		return nil, fmt.Errorf("container %s is in a pending transfer", containerID)
+	}
+
+	drugsID, err := s.getIndexedIDs(ctx, containerDrugIndex, container.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, drugID := range drugsID {
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get drug: %w", err)
+		}
+
+		drug.ContainerID = ""
+
+		drugJSON, err := json.Marshal(drug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal drug: %w", err)
+		}
+		if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+			return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+		}
+
+		if err := s.delCompositeKey(ctx, containerDrugIndex, container.ID, drug.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	containersID, err := s.getIndexedIDs(ctx, containerChildIndex, container.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, childID := range containersID {
+		child, err := s.GetContainer(ctx, childID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get container: %w", err)
+		}
+
+		child.ParentID = ""
+		if err := s.putContainer(ctx, child); err != nil {
+			return nil, err
+		}
+
+		if err := s.delCompositeKey(ctx, containerChildIndex, container.ID, child.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if container.ParentID != "" {
+		if err := s.delCompositeKey(ctx, containerChildIndex, container.ParentID, container.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.delCompositeKey(ctx, ownerContainerIndex, container.OwnerID, container.ID); err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().DelState(container.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete container from world state: %w", err)
+	}
+	log.Printf("Container %s unpacked: drugs %v, containers %v\n", container.ID, drugsID, containersID)
+
+	if err := s.emitContainerChanged(ctx, event.TypeContainerDisaggregated, container, containersID, drugsID); err != nil {
+		return nil, err
+	}
+
+	return container, nil
+}
+
+func (s *SmartContract) GetContainer(ctx contractapi.TransactionContextInterface, id string) (*model.Container, error) {
+	containerJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if containerJSON == nil {
+		return nil, fmt.Errorf("container %s does not exist", id)
+	}
+
+	var container model.Container
+	if err := json.Unmarshal(containerJSON, &container); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal container: %w", err)
+	}
+	if container.DocType != model.DocTypeContainer {
+		return nil, fmt.Errorf("container %s does not exist", id)
+	}
+
+	return &container, nil
+}
+
+// ExpandContainer resolves a container to every container and drug nested in it.
+func (s *SmartContract) ExpandContainer(ctx contractapi.TransactionContextInterface, containerID string) (*model.ContainerContents, error) {
+	if err := gs1.ValidateSSCC(containerID); err != nil {
+		return nil, err
+	}
+
+	containers, drugsID, err := s.expandContainer(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	drugs := make([]*model.Drug, 0, len(drugsID))
+	for _, drugID := range drugsID {
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get drug: %w", err)
+		}
+		drugs = append(drugs, drug)
+	}
+
+	return &model.ContainerContents{
+		Containers: containers,
+		Drugs:      drugs,
+	}, nil
+}
+
+func (s *SmartContract) GetMyContainers(ctx contractapi.TransactionContextInterface) ([]*model.Container, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	containersID, err := s.getIndexedIDs(ctx, ownerContainerIndex, org.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]*model.Container, 0, len(containersID))
+	for _, containerID := range containersID {
+		container, err := s.GetContainer(ctx, containerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get container: %w", err)
+		}
+		containers = append(containers, container)
+	}
+
+	return containers, nil
+}
+
+// expandContainer walks the container tree breadth first and returns the root
+// followed by every nested container, and the IDs of all packed drugs.
+func (s *SmartContract) expandContainer(ctx contractapi.TransactionContextInterface, containerID string) ([]*model.Container, []string, error) {
+	root, err := s.GetContainer(ctx, containerID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get container: %w", err)
+	}
+
+	containers := []*model.Container{root}
+	drugsID := make([]string, 0)
+	for i := 0; i < len(containers); i++ {
+		packedDrugsID, err := s.getIndexedIDs(ctx, containerDrugIndex, containers[i].ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		drugsID = append(drugsID, packedDrugsID...)
+
+		childrenID, err := s.getIndexedIDs(ctx, containerChildIndex, containers[i].ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, childID := range childrenID {
+			child, err := s.GetContainer(ctx, childID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get container: %w", err)
+			}
+			containers = append(containers, child)
+		}
+	}
+
+	return containers, drugsID, nil
+}
+
+// prepareTransferContainers marks the listed top-level containers and everything
+// nested in them as in transit, and returns their SSCCs and the drugs they hold.
+func (s *SmartContract) prepareTransferContainers(ctx contractapi.TransactionContextInterface, org *model.Organization, containersID []*string) ([]string, []string, error) {
+	transferContainersID := make([]string, 0, len(containersID))
+	drugsID := make([]string, 0)
+	for _, containerID := range containersID {
+		if containerID == nil {
+			return nil, nil, fmt.Errorf("container ID must not be null")
+		}
+		if err := gs1.ValidateSSCC(*containerID); err != nil {
+			return nil, nil, err
+		}
+
+		containers, packedDrugsID, err := s.expandContainer(ctx, *containerID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		root := containers[0]
+		if root.OwnerID != org.ID {
+			return nil, nil, fmt.Errorf("container %s does not belong to the sender", root.ID)
+		}
+		if root.IsTransferred {
+			return nil, nil, fmt.Errorf("container %s has already been transferred", root.ID)
+		}
+		if root.ParentID != "" {
+			return nil, nil, fmt.Errorf("container %s is packed in container %s, transfer the outer container or disaggregate it first", root.ID, root.ParentID)
+		}
+
+		for _, container := range containers {
+			container.IsTransferred = true
+			if err := s.putContainer(ctx, container); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		transferContainersID = append(transferContainersID, root.ID)
+		drugsID = append(drugsID, packedDrugsID...)
+	}
+
+	return transferContainersID, drugsID, nil
+}
+
+// releaseContainer ends the transit of a container tree, handing it to ownerID
+// and keeping the owner-container index in step with the drugs inside.
+func (s *SmartContract) releaseContainer(ctx contractapi.TransactionContextInterface, containerID string, ownerID string) error {
+	containers, _, err := s.expandContainer(ctx, containerID)
+	if err != nil {
+		return err
+	}
+
+	value := []byte{0x00}
+	for _, container := range containers {
+		if container.OwnerID != ownerID {
+			if err := s.delCompositeKey(ctx, ownerContainerIndex, container.OwnerID, container.ID); err != nil {
+				return err
+			}
+
+			ownerContainerIndexKey, err := ctx.GetStub().CreateCompositeKey(ownerContainerIndex, []string{ownerID, container.ID})
+			if err != nil {
+				return fmt.Errorf("failed to create composite key: %w", err)
+			}
+			if err := ctx.GetStub().PutState(ownerContainerIndexKey, value); err != nil {
+				return fmt.Errorf("failed to put owner-container index to world state: %w", err)
+			}
+
+			container.OwnerID = ownerID
+		}
+
+		container.IsTransferred = false
+		if err := s.putContainer(ctx, container); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// settlePartialContainers hands each container of a partially accepted transfer to
+// the receiver or back to the sender. A container travels as one unit, so all of
+// its drugs must share the same outcome.
+func (s *SmartContract) settlePartialContainers(ctx contractapi.TransactionContextInterface, transfer *model.Transfer, outcomes map[string]*model.TransferDrugOutcome) error {
+	for _, containerID := range transfer.ContainersID {
+		_, drugsID, err := s.expandContainer(ctx, containerID)
+		if err != nil {
+			return err
+		}
+
+		receivedCount := 0
+		for _, drugID := range drugsID {
+			if outcomes[drugID].Outcome == model.DrugOutcomeReceived {
+				receivedCount++
+			}
+		}
+
+		ownerID := transfer.SenderID
+		switch receivedCount {
+		case 0:
+		case len(drugsID):
+			ownerID = transfer.ReceiverID
+		default:
+			return fmt.Errorf("drugs in container %s must all be received or all be refused", containerID)
+		}
+
+		if err := s.releaseContainer(ctx, containerID, ownerID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) getOrCreateContainer(ctx contractapi.TransactionContextInterface, org *model.Organization, containerID string, level string) (*model.Container, bool, error) {
+	containerJSON, err := ctx.GetStub().GetState(containerID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read from world state: %w", err)
+	}
+
+	if containerJSON == nil {
+		return &model.Container{
+			DocType: model.DocTypeContainer,
+			ID:      containerID,
+			Level:   level,
+			OwnerID: org.ID,
+		}, true, nil
+	}
+
+	var container model.Container
+	if err := json.Unmarshal(containerJSON, &container); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal container: %w", err)
+	}
+	if container.DocType != model.DocTypeContainer {
+		return nil, false, fmt.Errorf("%s is not a container", containerID)
+	}
+	if container.OwnerID != org.ID {
+		return nil, false, fmt.Errorf("container %s does not belong to the caller", containerID)
+	}
+	if container.IsTransferred {
+		return nil, false, fmt.Errorf("container %s is in a pending transfer", containerID)
+	}
+	if container.Level != level {
+		return nil, false, fmt.Errorf("container %s is a %s, not a %s", containerID, container.Level, level)
+	}
+
+	return &container, false, nil
+}
+
+func (s *SmartContract) putContainer(ctx contractapi.TransactionContextInterface, container *model.Container) error {
+	containerJSON, err := json.Marshal(container)
+	if err != nil {
+		return fmt.Errorf("failed to marshal container: %w", err)
+	}
+	if err := ctx.GetStub().PutState(container.ID, containerJSON); err != nil {
+		return fmt.Errorf("failed to put container to world state: %w", err)
+	}
+
+	return nil
+}
+
+// getIndexedIDs returns the second attribute of every index entry under the first.
+func (s *SmartContract) getIndexedIDs(ctx contractapi.TransactionContextInterface, index string, attribute string) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{attribute})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s index: %w", index, err)
+	}
+	defer iterator.Close()
+
+	ids := make([]string, 0)
+	for iterator.HasNext() {
+		responseRange, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate %s index: %w", index, err)
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %w", err)
+		}
+
+		if len(compositeKeyParts) > 1 {
+			ids = append(ids, compositeKeyParts[1])
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *SmartContract) delCompositeKey(ctx contractapi.TransactionContextInterface, index string, attributes ...string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(index, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %w", err)
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("failed to delete %s index from world state: %w", index, err)
+	}
+
+	return nil
+}
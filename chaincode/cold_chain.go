@@ -0,0 +1,252 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// RecordConditionReading logs a temperature and humidity reading taken during a
+// pending transfer and checks it against the storage ranges of every batch in the
+// shipment. A breach marks the transfer as having had an excursion, which the
+// receiver must then override or refuse.
+func (s *SmartContract) RecordConditionReading(ctx contractapi.TransactionContextInterface, req string) (*model.ConditionReading, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	var recordReading dto.RecordConditionReading
+	if err := dto.Decode(req, &recordReading); err != nil {
+		return nil, err
+	}
+
+	transfer, err := s.GetTransfer(ctx, recordReading.TransferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+	if org.ID != transfer.SenderID && org.ID != transfer.ReceiverID {
+		return nil, fmt.Errorf("only the sender or receiver can record readings for transfer %s", transfer.ID)
+	}
+	if transfer.Status != model.TransferStatusPending {
+		return nil, fmt.Errorf("transfer %s is no longer in transit, status is %s", transfer.ID, transfer.Status)
+	}
+
+	recordedAt, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	measuredAt := recordedAt
+	if recordReading.MeasuredAt != nil {
+		if recordReading.MeasuredAt.After(recordedAt) {
+			return nil, fmt.Errorf("measurement time %s is after the ledger time %s", recordReading.MeasuredAt.Format("2006-01-02T15:04:05Z07:00"), recordedAt.Format("2006-01-02T15:04:05Z07:00"))
+		}
+		if recordReading.MeasuredAt.Before(transfer.TransferDate) {
+			return nil, fmt.Errorf("measurement time %s is before transfer %s was shipped at %s", recordReading.MeasuredAt.Format("2006-01-02T15:04:05Z07:00"), transfer.ID, transfer.TransferDate.Format("2006-01-02T15:04:05Z07:00"))
+		}
+		measuredAt = *recordReading.MeasuredAt
+	}
+
+	readingID, err := s.generateModelId(ctx, conditionReadingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	reading := model.ConditionReading{
+		DocType:     model.DocTypeConditionReading,
+		Humidity:    recordReading.Humidity,
+		ID:          readingID,
+		MeasuredAt:  measuredAt,
+		RecordedAt:  recordedAt,
+		RecordedBy:  org.ID,
+		SensorID:    recordReading.SensorID,
+		Temperature: recordReading.Temperature,
+		TransferID:  transfer.ID,
+	}
+
+	batches, err := s.getTransferBatches(ctx, transfer.ID)
+	if err != nil {
+		return nil, err
+	}
+	reading.Excursions = detectExcursions(&reading, batches)
+
+	readingJSON, err := json.Marshal(reading)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal condition reading: %w", err)
+	}
+	if err := ctx.GetStub().PutState(reading.ID, readingJSON); err != nil {
+		return nil, fmt.Errorf("failed to put condition reading to world state: %w", err)
+	}
+
+	transferReadingIndexKey, err := ctx.GetStub().CreateCompositeKey(transferReadingIndex, []string{transfer.ID, reading.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %w", err)
+	}
+	if err := ctx.GetStub().PutState(transferReadingIndexKey, []byte{0x00}); err != nil {
+		return nil, fmt.Errorf("failed to put transfer-reading index to world state: %w", err)
+	}
+
+	excursionBatchesID := make([]string, 0)
+	for _, excursion := range reading.Excursions {
+		excursionBatchesID = appendUnique(excursionBatchesID, excursion.BatchID)
+	}
+
+	payload := event.ConditionReading{
+		ExcursionBatchesID: excursionBatchesID,
+		ReadingID:          reading.ID,
+		RecordedBy:         org.ID,
+		TransferID:         transfer.ID,
+	}
+	recorded, err := event.New(event.TypeConditionReadingRecorded, payload)
+	if err != nil {
+		return nil, err
+	}
+	events := []*event.Event{recorded}
+
+	if len(excursionBatchesID) > 0 {
+		transfer.HasExcursion = true
+		for _, batchID := range excursionBatchesID {
+			transfer.ExcursionBatchesID = appendUnique(transfer.ExcursionBatchesID, batchID)
+		}
+
+		transferJSON, err := json.Marshal(transfer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal transfer: %w", err)
+		}
+		if err := ctx.GetStub().PutState(transfer.ID, transferJSON); err != nil {
+			return nil, fmt.Errorf("failed to put transfer to world state: %w", err)
+		}
+
+		excursion, err := event.New(event.TypeConditionExcursion, payload)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, excursion)
+	}
+
+	if err := s.emitEvents(ctx, events...); err != nil {
+		return nil, err
+	}
+
+	return &reading, nil
+}
+
+func (s *SmartContract) GetTransferConditionReadings(ctx contractapi.TransactionContextInterface, transferID string) ([]*model.ConditionReading, error) {
+	readingsID, err := s.getIndexedIDs(ctx, transferReadingIndex, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	readings := make([]*model.ConditionReading, 0, len(readingsID))
+	for _, readingID := range readingsID {
+		readingJSON, err := ctx.GetStub().GetState(readingID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %w", err)
+		}
+		if readingJSON == nil {
+			return nil, fmt.Errorf("condition reading %s does not exist", readingID)
+		}
+
+		var reading model.ConditionReading
+		if err := json.Unmarshal(readingJSON, &reading); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal condition reading: %w", err)
+		}
+		readings = append(readings, &reading)
+	}
+
+	return readings, nil
+}
+
+// getTransferBatches returns the batches of the drugs in a transfer in the order
+// they are first met, so every endorser evaluates them identically.
+func (s *SmartContract) getTransferBatches(ctx contractapi.TransactionContextInterface, transferID string) ([]*model.Batch, error) {
+	drugs, err := s.GetDrugByTransfer(ctx, transferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transferred drugs: %w", err)
+	}
+
+	batchesID := make([]string, 0)
+	for _, drug := range drugs {
+		batchesID = appendUnique(batchesID, drug.BatchID)
+	}
+
+	batches := make([]*model.Batch, 0, len(batchesID))
+	for _, batchID := range batchesID {
+		batch, err := s.GetBatch(ctx, batchID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get batch: %w", err)
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
+
+// checkExcursionOverride refuses to hand the receiver units from a batch whose
+// storage range was breached unless the receiver explicitly overrides it.
+func checkExcursionOverride(transfer *model.Transfer, drug *model.Drug, override bool) error {
+	if !transfer.HasExcursion {
+		return nil
+	}
+
+	for _, batchID := range transfer.ExcursionBatchesID {
+		if batchID != drug.BatchID {
+			continue
+		}
+		if !override {
+			return fmt.Errorf("drug %s of batch %s had a cold-chain excursion in transfer %s, refuse it or accept with an explicit override", drug.ID, batchID, transfer.ID)
+		}
+		transfer.IsExcursionOverridden = true
+	}
+
+	return nil
+}
+
+func detectExcursions(reading *model.ConditionReading, batches []*model.Batch) []*model.ConditionExcursion {
+	excursions := make([]*model.ConditionExcursion, 0)
+	for _, batch := range batches {
+		if batch.StorageConditions == nil {
+			continue
+		}
+
+		if excursion := checkConditionRange(batch.ID, model.ConditionTemperature, reading.Temperature, batch.StorageConditions.Temperature); excursion != nil {
+			excursions = append(excursions, excursion)
+		}
+		if excursion := checkConditionRange(batch.ID, model.ConditionHumidity, reading.Humidity, batch.StorageConditions.Humidity); excursion != nil {
+			excursions = append(excursions, excursion)
+		}
+	}
+
+	return excursions
+}
+
+func checkConditionRange(batchID string, condition string, value *float64, allowed *model.ConditionRange) *model.ConditionExcursion {
+	if value == nil || allowed == nil {
+		return nil
+	}
+	if *value >= allowed.Min && *value <= allowed.Max {
+		return nil
+	}
+
+	return &model.ConditionExcursion{
+		BatchID:   batchID,
+		Condition: condition,
+		Max:       allowed.Max,
+		Min:       allowed.Min,
+		Value:     *value,
+	}
+}
+
+func appendUnique(ids []string, id string) []string {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,326 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/epcis"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// DecommissionDrug asks the witness to confirm that drugs were destroyed, used as
+// samples, or stolen. Until the witness confirms, the drugs stay in the caller's
+// stock but are locked against transfer, dispensing and packing.
+func (s *SmartContract) DecommissionDrug(ctx contractapi.TransactionContextInterface, req string) (*model.Decommission, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var decommissionDrug dto.DecommissionDrug
+	if err := dto.Decode(req, &decommissionDrug); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkWitness(ctx, org, decommissionDrug.WitnessID); err != nil {
+		return nil, err
+	}
+
+	requestedAt, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decommissionID, err := s.generateModelId(ctx, decommissionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, drugID := range decommissionDrug.DrugsID {
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkDecommissionable(ctx, drug, org); err != nil {
+			return nil, err
+		}
+
+		drug.DecommissionID = decommissionID
+		if err := s.putDrug(ctx, drug); err != nil {
+			return nil, err
+		}
+	}
+
+	decommission := model.Decommission{
+		CertificateHash: strings.ToLower(decommissionDrug.CertificateHash),
+		DocType:         model.DocTypeDecommission,
+		DrugsID:         decommissionDrug.DrugsID,
+		ID:              decommissionID,
+		Kind:            decommissionDrug.Kind,
+		OwnerID:         org.ID,
+		ReasonCode:      decommissionDrug.ReasonCode,
+		RequestedAt:     requestedAt,
+		Status:          model.DecommissionStatusPending,
+		WitnessID:       decommissionDrug.WitnessID,
+	}
+	if err := s.putDecommission(ctx, &decommission); err != nil {
+		return nil, err
+	}
+	log.Printf("Decommissioning requested by %s (%s): %v\n", org.ID, decommission.Kind, decommission.DrugsID)
+
+	if err := s.emitDecommissionEvent(ctx, event.TypeDecommissionRequested, &decommission); err != nil {
+		return nil, err
+	}
+
+	return &decommission, nil
+}
+
+// ConfirmDecommission is called by the witness to attest a pending decommission.
+// The drugs then leave their owner's inventory for good.
+func (s *SmartContract) ConfirmDecommission(ctx contractapi.TransactionContextInterface, decommissionID string) (*model.Decommission, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decommission, err := s.getPendingDecommission(ctx, decommissionID)
+	if err != nil {
+		return nil, err
+	}
+	if org.ID != decommission.WitnessID {
+		return nil, fmt.Errorf("only witness %s can confirm decommission %s", decommission.WitnessID, decommission.ID)
+	}
+
+	decommissionedAt, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, drugID := range decommission.DrugsID {
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.delCompositeKey(ctx, ownerDrugIndex, drug.OwnerID, drug.ID); err != nil {
+			return nil, err
+		}
+
+		drug.IsDecommissioned = true
+		if err := s.putDrug(ctx, drug); err != nil {
+			return nil, err
+		}
+	}
+
+	decommission.DecommissionedAt = decommissionedAt
+	decommission.Status = model.DecommissionStatusConfirmed
+	if err := s.putDecommission(ctx, decommission); err != nil {
+		return nil, err
+	}
+	log.Printf("Decommission %s confirmed by %s\n", decommission.ID, org.ID)
+
+	if err := s.emitDecommissionEvent(ctx, event.TypeDrugDecommissioned, decommission); err != nil {
+		return nil, err
+	}
+
+	return decommission, nil
+}
+
+// RejectDecommission is called by the witness to refuse a pending decommission.
+// The drugs are unlocked and stay in their owner's stock.
+func (s *SmartContract) RejectDecommission(ctx contractapi.TransactionContextInterface, decommissionID string) (*model.Decommission, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decommission, err := s.getPendingDecommission(ctx, decommissionID)
+	if err != nil {
+		return nil, err
+	}
+	if org.ID != decommission.WitnessID {
+		return nil, fmt.Errorf("only witness %s can reject decommission %s", decommission.WitnessID, decommission.ID)
+	}
+
+	return s.releaseDecommission(ctx, decommission, model.DecommissionStatusRejected, event.TypeDecommissionRejected)
+}
+
+// CancelDecommission lets the owner withdraw a decommission the witness has not
+// confirmed yet.
+func (s *SmartContract) CancelDecommission(ctx contractapi.TransactionContextInterface, decommissionID string) (*model.Decommission, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decommission, err := s.getPendingDecommission(ctx, decommissionID)
+	if err != nil {
+		return nil, err
+	}
+	if org.ID != decommission.OwnerID {
+		return nil, fmt.Errorf("only owner %s can cancel decommission %s", decommission.OwnerID, decommission.ID)
+	}
+
+	return s.releaseDecommission(ctx, decommission, model.DecommissionStatusCancelled, event.TypeDecommissionCancelled)
+}
+
+func (s *SmartContract) GetDecommission(ctx contractapi.TransactionContextInterface, id string) (*model.Decommission, error) {
+	decommissionJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if decommissionJSON == nil {
+		return nil, fmt.Errorf("decommission %s does not exist", id)
+	}
+
+	var decommission model.Decommission
+	if err := json.Unmarshal(decommissionJSON, &decommission); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal decommission: %w", err)
+	}
+	if decommission.DocType != model.DocTypeDecommission {
+		return nil, fmt.Errorf("decommission %s does not exist", id)
+	}
+
+	return &decommission, nil
+}
+
+func (s *SmartContract) getPendingDecommission(ctx contractapi.TransactionContextInterface, id string) (*model.Decommission, error) {
+	decommission, err := s.GetDecommission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if decommission.Status != model.DecommissionStatusPending {
+		return nil, fmt.Errorf("decommission %s is not pending, status is %s", decommission.ID, decommission.Status)
+	}
+
+	return decommission, nil
+}
+
+func (s *SmartContract) releaseDecommission(ctx contractapi.TransactionContextInterface, decommission *model.Decommission, status string, eventType string) (*model.Decommission, error) {
+	for _, drugID := range decommission.DrugsID {
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, err
+		}
+
+		drug.DecommissionID = ""
+		if err := s.putDrug(ctx, drug); err != nil {
+			return nil, err
+		}
+	}
+
+	decommission.Status = status
+	if err := s.putDecommission(ctx, decommission); err != nil {
+		return nil, err
+	}
+
+	if err := s.emitDecommissionEvent(ctx, eventType, decommission); err != nil {
+		return nil, err
+	}
+
+	return decommission, nil
+}
+
+func (s *SmartContract) putDrug(ctx contractapi.TransactionContextInterface, drug *model.Drug) error {
+	drugJSON, err := json.Marshal(drug)
+	if err != nil {
+		return fmt.Errorf("failed to marshal drug: %w", err)
+	}
+	if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+		return fmt.Errorf("failed to put drug to world state: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SmartContract) putDecommission(ctx contractapi.TransactionContextInterface, decommission *model.Decommission) error {
+	decommissionJSON, err := json.Marshal(decommission)
+	if err != nil {
+		return fmt.Errorf("failed to marshal decommission: %w", err)
+	}
+	if err := ctx.GetStub().PutState(decommission.ID, decommissionJSON); err != nil {
+		return fmt.Errorf("failed to put decommission to world state: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SmartContract) emitDecommissionEvent(ctx contractapi.TransactionContextInterface, eventType string, decommission *model.Decommission) error {
+	return s.emitEvent(ctx, eventType, event.DecommissionChanged{
+		DecommissionID: decommission.ID,
+		DrugsID:        decommission.DrugsID,
+		Kind:           decommission.Kind,
+		OwnerID:        decommission.OwnerID,
+		ReasonCode:     decommission.ReasonCode,
+		Status:         decommission.Status,
+		WitnessID:      decommission.WitnessID,
+	})
+}
+
+func (s *SmartContract) checkWitness(ctx contractapi.TransactionContextInterface, org *model.Organization, witnessID string) error {
+	if witnessID == org.ID {
+		return fmt.Errorf("organization %s cannot witness its own decommissioning", org.ID)
+	}
+
+	witness, err := s.GetOrganization(ctx, witnessID)
+	if err != nil {
+		return fmt.Errorf("failed to get witness: %w", err)
+	}
+	if witness.Status == model.OrgStatusSuspended {
+		return fmt.Errorf("witness %s is suspended", witnessID)
+	}
+
+	return nil
+}
+
+// checkDecommissionable allows recalled and non-saleable drugs, since those are
+// usually what gets destroyed, but not drugs under a hold.
+func (s *SmartContract) checkDecommissionable(ctx contractapi.TransactionContextInterface, drug *model.Drug, org *model.Organization) error {
+	if drug.OwnerID != org.ID {
+		return fmt.Errorf("drug %s does not belong to the caller", drug.ID)
+	}
+	if drug.IsDecommissioned {
+		return fmt.Errorf("drug %s has already been decommissioned", drug.ID)
+	}
+	if drug.DecommissionID != "" {
+		return fmt.Errorf("drug %s is awaiting confirmation of decommission %s", drug.ID, drug.DecommissionID)
+	}
+	if drug.IsDispensed {
+		return fmt.Errorf("drug %s has been dispensed", drug.ID)
+	}
+	if drug.IsTransferred {
+		return fmt.Errorf("drug %s is in a pending transfer", drug.ID)
+	}
+	if drug.ContainerID != "" {
+		return fmt.Errorf("drug %s is packed in container %s, disaggregate it first", drug.ID, drug.ContainerID)
+	}
+
+	holdID, err := s.findActiveHold(ctx, drug)
+	if err != nil {
+		return err
+	}
+	if holdID != "" {
+		return fmt.Errorf("drug %s is under quarantine hold %s", drug.ID, holdID)
+	}
+
+	return nil
+}
+
+func decommissioningEvent(decommission *model.Decommission, drugs []*model.Drug) *epcis.ObjectEvent {
+	bizStep, disposition := epcis.BizStepDecommissioning, epcis.DispositionNonSellableOther
+	switch decommission.Kind {
+	case model.DecommissionKindDestroyed:
+		bizStep, disposition = epcis.BizStepDestroying, epcis.DispositionDestroyed
+	case model.DecommissionKindStolen:
+		disposition = epcis.DispositionStolen
+	}
+
+	owner := orgURIPrefix + decommission.OwnerID
+	return epcis.NewObjectEvent(decommission.DecommissionedAt, epcis.ActionObserve, bizStep, disposition, drugEPCs(drugs)...).
+		At(owner, owner)
+}
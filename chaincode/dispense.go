@@ -0,0 +1,153 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// DispenseDrug hands drugs from a pharmacy's stock to a patient in one step. The
+// drugs stay with the pharmacy on the ledger but are marked dispensed, which is
+// terminal: they cannot be transferred, packed or dispensed again.
+func (s *SmartContract) DispenseDrug(ctx contractapi.TransactionContextInterface, req string) (*model.Dispense, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if org.Type != model.OrgTypePharmacy {
+		return nil, fmt.Errorf("only pharmacies can dispense drugs")
+	}
+
+	var dispenseDrug dto.DispenseDrug
+	if err := dto.Decode(req, &dispenseDrug); err != nil {
+		return nil, err
+	}
+
+	dispensedAt, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dispenseID, err := s.generateModelId(ctx, dispenseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	batches := make(map[string]*model.Batch)
+	for _, drugID := range dispenseDrug.DrugsID {
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkDispensable(ctx, drug, org); err != nil {
+			return nil, err
+		}
+		if err := s.checkNotExpired(ctx, batches, drug, dispensedAt); err != nil {
+			return nil, err
+		}
+
+		drug.DispenseID = dispenseID
+		drug.IsDispensed = true
+
+		drugJSON, err := json.Marshal(drug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal drug: %w", err)
+		}
+		if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+			return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+		}
+	}
+
+	dispense := model.Dispense{
+		DispensedAt:    dispensedAt,
+		DocType:        model.DocTypeDispense,
+		DrugsID:        dispenseDrug.DrugsID,
+		ID:             dispenseID,
+		PatientRef:     dispenseDrug.PatientRef,
+		PharmacyID:     org.ID,
+		PrescriptionID: dispenseDrug.PrescriptionID,
+	}
+
+	dispenseJSON, err := json.Marshal(dispense)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dispense: %w", err)
+	}
+	if err := ctx.GetStub().PutState(dispense.ID, dispenseJSON); err != nil {
+		return nil, fmt.Errorf("failed to put dispense to world state: %w", err)
+	}
+	log.Printf("Drugs dispensed by %s: %v\n", org.ID, dispense.DrugsID)
+
+	if err := s.emitEvent(ctx, event.TypeDrugDispensed, event.DrugDispensed{
+		DispenseID:     dispense.ID,
+		DrugsID:        dispense.DrugsID,
+		PharmacyID:     dispense.PharmacyID,
+		PrescriptionID: dispense.PrescriptionID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &dispense, nil
+}
+
+func (s *SmartContract) GetDispense(ctx contractapi.TransactionContextInterface, id string) (*model.Dispense, error) {
+	dispenseJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if dispenseJSON == nil {
+		return nil, fmt.Errorf("dispense %s does not exist", id)
+	}
+
+	var dispense model.Dispense
+	if err := json.Unmarshal(dispenseJSON, &dispense); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dispense: %w", err)
+	}
+
+	return &dispense, nil
+}
+
+func (s *SmartContract) checkDispensable(ctx contractapi.TransactionContextInterface, drug *model.Drug, org *model.Organization) error {
+	if drug.OwnerID != org.ID {
+		return fmt.Errorf("drug %s does not belong to the caller", drug.ID)
+	}
+	if drug.IsDispensed {
+		return fmt.Errorf("drug %s has already been dispensed", drug.ID)
+	}
+	if drug.IsDecommissioned {
+		return fmt.Errorf("drug %s has been decommissioned", drug.ID)
+	}
+	if drug.DecommissionID != "" {
+		return fmt.Errorf("drug %s is awaiting confirmation of decommission %s", drug.ID, drug.DecommissionID)
+	}
+	if drug.IsTransferred {
+		return fmt.Errorf("drug %s is in a pending transfer", drug.ID)
+	}
+	if drug.IsRecalled {
+		return fmt.Errorf("drug %s belongs to recalled batch %s", drug.ID, drug.BatchID)
+	}
+	if drug.IsNonSaleable {
+		return fmt.Errorf("drug %s was returned as non-saleable", drug.ID)
+	}
+	if drug.IsFlagged {
+		return fmt.Errorf("drug %s has open scan alerts", drug.ID)
+	}
+	if drug.ContainerID != "" {
+		return fmt.Errorf("drug %s is packed in container %s, disaggregate it first", drug.ID, drug.ContainerID)
+	}
+
+	holdID, err := s.findActiveHold(ctx, drug)
+	if err != nil {
+		return err
+	}
+	if holdID != "" {
+		return fmt.Errorf("drug %s is under quarantine hold %s", drug.ID, holdID)
+	}
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/epcis"
+	"github.com/AryaJayadi/MedTrace_chaincode/gs1"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	drugURIPrefix     = "urn:medtrace:drug:"
+	orgURIPrefix      = "urn:medtrace:org:"
+	transferURIPrefix = "urn:medtrace:transfer:"
+)
+
+func (s *SmartContract) ExportDrugEPCIS(ctx contractapi.TransactionContextInterface, drugID string) (string, error) {
+	drug, err := s.GetDrug(ctx, drugID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get drug: %w", err)
+	}
+
+	batch, err := s.GetBatch(ctx, drug.BatchID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	productionDate, err := s.getProductionDate(ctx, batch)
+	if err != nil {
+		return "", err
+	}
+	events := []*epcis.ObjectEvent{commissioningEvent(batch, productionDate, []*model.Drug{drug})}
+
+	transferIDs, err := s.getDrugTransferIDs(ctx, drug.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, transferID := range transferIDs {
+		transfer, err := s.GetTransfer(ctx, transferID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get transfer: %w", err)
+		}
+
+		transferEvents, err := s.transferEPCISEvents(ctx, transfer, []*model.Drug{drug})
+		if err != nil {
+			return "", err
+		}
+		events = append(events, transferEvents...)
+	}
+
+	if drug.DispenseID != "" {
+		dispense, err := s.GetDispense(ctx, drug.DispenseID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get dispense: %w", err)
+		}
+
+		pharmacy := orgURIPrefix + dispense.PharmacyID
+		events = append(events, epcis.NewObjectEvent(dispense.DispensedAt, epcis.ActionObserve, epcis.BizStepDispensing, epcis.DispositionDispensed, drugEPC(drug)).
+			At(pharmacy, pharmacy))
+	}
+
+	if drug.IsDecommissioned && drug.DecommissionID != "" {
+		decommission, err := s.GetDecommission(ctx, drug.DecommissionID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get decommission: %w", err)
+		}
+		events = append(events, decommissioningEvent(decommission, []*model.Drug{drug}))
+	}
+
+	return s.renderEPCISDocument(ctx, events)
+}
+
+func (s *SmartContract) ExportBatchEPCIS(ctx contractapi.TransactionContextInterface, batchID string) (string, error) {
+	batch, err := s.GetBatch(ctx, batchID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	drugs, err := s.GetDrugByBatch(ctx, batchID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get batch drugs: %w", err)
+	}
+	if len(drugs) == 0 {
+		return "", fmt.Errorf("batch %s has no drugs", batchID)
+	}
+
+	productionDate, err := s.getProductionDate(ctx, batch)
+	if err != nil {
+		return "", err
+	}
+
+	return s.renderEPCISDocument(ctx, []*epcis.ObjectEvent{commissioningEvent(batch, productionDate, drugs)})
+}
+
+func (s *SmartContract) ExportTransferEPCIS(ctx contractapi.TransactionContextInterface, transferID string) (string, error) {
+	transfer, err := s.GetTransfer(ctx, transferID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get transfer: %w", err)
+	}
+
+	drugs, err := s.GetDrugByTransfer(ctx, transferID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get transferred drugs: %w", err)
+	}
+	if len(drugs) == 0 {
+		return "", fmt.Errorf("transfer %s has no drugs", transferID)
+	}
+
+	events, err := s.transferEPCISEvents(ctx, transfer, drugs)
+	if err != nil {
+		return "", err
+	}
+
+	return s.renderEPCISDocument(ctx, events)
+}
+
+// renderEPCISDocument leaves out events whose time is still unknown after the
+// history fallbacks, so legacy records do not make the whole export fail.
+func (s *SmartContract) renderEPCISDocument(ctx contractapi.TransactionContextInterface, events []*epcis.ObjectEvent) (string, error) {
+	creationDate, err := s.getTxTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	document := epcis.NewDocument(creationDate)
+	for _, event := range events {
+		if !event.EventTime.IsZero() {
+			document.AddEvents(event)
+		}
+	}
+	if err := document.Validate(); err != nil {
+		return "", fmt.Errorf("invalid EPCIS document: %w", err)
+	}
+
+	documentJSON, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal EPCIS document: %w", err)
+	}
+
+	return string(documentJSON), nil
+}
+
+func commissioningEvent(batch *model.Batch, productionDate time.Time, drugs []*model.Drug) *epcis.ObjectEvent {
+	manufacturerID := batch.ManufacturerID
+	if manufacturerID == "" {
+		manufacturerID = batch.ManufacturerName
+	}
+
+	lotNumber := batch.LotNumber
+	if lotNumber == "" {
+		lotNumber = batch.ID
+	}
+
+	return epcis.NewObjectEvent(productionDate, epcis.ActionAdd, epcis.BizStepCommissioning, epcis.DispositionActive, drugEPCs(drugs)...).
+		At(orgURIPrefix+manufacturerID, orgURIPrefix+manufacturerID).
+		WithILMD(epcis.ILMDLotNumber, lotNumber).
+		WithILMD(epcis.ILMDItemExpirationDate, batch.ExpiryDate.Format("2006-01-02"))
+}
+
+// transferEPCISEvents renders a transfer as a shipping event followed by the
+// receiver's outcome. Received drugs get a receiving event; drugs the receiver
+// rejected or refused get a receiving event with the returned disposition, and a
+// cancelled transfer is voided at the time the sender cancelled it.
+func (s *SmartContract) transferEPCISEvents(ctx contractapi.TransactionContextInterface, transfer *model.Transfer, drugs []*model.Drug) ([]*epcis.ObjectEvent, error) {
+	sender := orgURIPrefix + transfer.SenderID
+	receiver := orgURIPrefix + transfer.ReceiverID
+	bizTransaction := transferURIPrefix + transfer.ID
+
+	events := []*epcis.ObjectEvent{
+		epcis.NewObjectEvent(transfer.TransferDate, epcis.ActionObserve, epcis.BizStepShipping, epcis.DispositionInTransit, drugEPCs(drugs)...).
+			At(sender, sender).
+			WithBizTransaction(epcis.BizTransactionDesadv, bizTransaction).
+			WithSource(epcis.PartyOwning, sender).
+			WithDestination(epcis.PartyOwning, receiver),
+	}
+
+	var received, returned []*model.Drug
+	switch transfer.Status {
+	case model.TransferStatusAccepted:
+		received = drugs
+	case model.TransferStatusRejected:
+		returned = drugs
+	case model.TransferStatusPartiallyAccepted:
+		for _, drug := range drugs {
+			if isDrugReceived(transfer, drug.ID) {
+				received = append(received, drug)
+			} else {
+				returned = append(returned, drug)
+			}
+		}
+	case model.TransferStatusCancelled:
+		_, cancelDate, err := s.getHistoryDates(ctx, transfer.ID)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, epcis.NewObjectEvent(cancelDate, epcis.ActionObserve, epcis.BizStepVoidShipping, epcis.DispositionActive, drugEPCs(drugs)...).
+			At(sender, sender).
+			WithBizTransaction(epcis.BizTransactionDesadv, bizTransaction))
+	}
+
+	receiveDate := transfer.ReceiveDate
+	if receiveDate.IsZero() && (len(received) > 0 || len(returned) > 0) {
+		// Transfers processed before the receive date was stored were last
+		// written when the receiver processed them.
+		_, lastModified, err := s.getHistoryDates(ctx, transfer.ID)
+		if err != nil {
+			return nil, err
+		}
+		receiveDate = lastModified
+	}
+
+	if len(received) > 0 {
+		events = append(events, epcis.NewObjectEvent(receiveDate, epcis.ActionObserve, epcis.BizStepReceiving, epcis.DispositionInProgress, drugEPCs(received)...).
+			At(receiver, receiver).
+			WithBizTransaction(epcis.BizTransactionDesadv, bizTransaction).
+			WithSource(epcis.PartyOwning, sender).
+			WithDestination(epcis.PartyOwning, receiver))
+	}
+	if len(returned) > 0 {
+		events = append(events, epcis.NewObjectEvent(receiveDate, epcis.ActionObserve, epcis.BizStepReceiving, epcis.DispositionReturned, drugEPCs(returned)...).
+			At(receiver, sender).
+			WithBizTransaction(epcis.BizTransactionDesadv, bizTransaction))
+	}
+
+	return events, nil
+}
+
+// getProductionDate falls back to the time the batch was first written for
+// batches created before the production date was required.
+func (s *SmartContract) getProductionDate(ctx contractapi.TransactionContextInterface, batch *model.Batch) (time.Time, error) {
+	if !batch.ProductionDate.IsZero() {
+		return batch.ProductionDate, nil
+	}
+
+	created, _, err := s.getHistoryDates(ctx, batch.ID)
+	return created, err
+}
+
+// getHistoryDates returns the times key was first and last written. Both are zero
+// when the key has no history.
+func (s *SmartContract) getHistoryDates(ctx contractapi.TransactionContextInterface, key string) (time.Time, time.Time, error) {
+	historyIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to get history for %s: %w", key, err)
+	}
+	defer historyIterator.Close()
+
+	var created, lastModified time.Time
+	for historyIterator.HasNext() {
+		response, err := historyIterator.Next()
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to iterate history for %s: %w", key, err)
+		}
+		timestamp := response.Timestamp.AsTime()
+		if created.IsZero() || timestamp.Before(created) {
+			created = timestamp
+		}
+		if timestamp.After(lastModified) {
+			lastModified = timestamp
+		}
+	}
+
+	return created, lastModified, nil
+}
+
+func drugEPCs(drugs []*model.Drug) []string {
+	epcs := make([]string, 0, len(drugs))
+	for _, drug := range drugs {
+		epcs = append(epcs, drugEPC(drug))
+	}
+	return epcs
+}
+
+func drugEPC(drug *model.Drug) string {
+	if drug.GTIN != "" {
+		return gs1.DigitalLink(drug.GTIN, drug.SerialNumber)
+	}
+	return drugURIPrefix + drug.ID
+}
@@ -0,0 +1,102 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func (s *SmartContract) emitEvent(ctx contractapi.TransactionContextInterface, eventType string, payload interface{}) error {
+	e, err := event.New(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	return s.emitEvents(ctx, e)
+}
+
+// emitEvents must be called at most once per transaction, since Fabric only keeps
+// the last event set by a transaction.
+func (s *SmartContract) emitEvents(ctx contractapi.TransactionContextInterface, events ...*event.Event) error {
+	envelope := event.Envelope{
+		Events:  events,
+		TxID:    ctx.GetStub().GetTxID(),
+		Version: event.Version,
+	}
+
+	envelopeJSON, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event envelope: %w", err)
+	}
+
+	if err := ctx.GetStub().SetEvent(event.Name, envelopeJSON); err != nil {
+		return fmt.Errorf("failed to set event: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SmartContract) emitTransferProcessed(ctx contractapi.TransactionContextInterface, eventType string, transfer *model.Transfer, receivedIDs []string, returnedIDs []string) error {
+	if receivedIDs == nil {
+		receivedIDs = []string{}
+	}
+	if returnedIDs == nil {
+		returnedIDs = []string{}
+	}
+
+	return s.emitEvent(ctx, eventType, event.TransferProcessed{
+		ReceivedDrugsID: receivedIDs,
+		ReceiverID:      transfer.ReceiverID,
+		ReturnedDrugsID: returnedIDs,
+		SenderID:        transfer.SenderID,
+		Status:          transfer.Status,
+		TransferID:      transfer.ID,
+	})
+}
+
+func (s *SmartContract) emitOrganizationChanged(ctx contractapi.TransactionContextInterface, eventType string, org *model.Organization) error {
+	return s.emitEvent(ctx, eventType, event.OrganizationChanged{
+		OrganizationID: org.ID,
+		Status:         org.Status,
+		Type:           org.Type,
+	})
+}
+
+func (s *SmartContract) emitContainerChanged(ctx contractapi.TransactionContextInterface, eventType string, container *model.Container, containersID []string, drugsID []string) error {
+	if containersID == nil {
+		containersID = []string{}
+	}
+	if drugsID == nil {
+		drugsID = []string{}
+	}
+
+	return s.emitEvent(ctx, eventType, event.ContainerChanged{
+		ContainerID:  container.ID,
+		ContainersID: containersID,
+		DrugsID:      drugsID,
+		Level:        container.Level,
+		OwnerID:      container.OwnerID,
+	})
+}
+
+func (s *SmartContract) emitProductChanged(ctx contractapi.TransactionContextInterface, eventType string, product *model.Product) error {
+	return s.emitEvent(ctx, eventType, event.ProductChanged{
+		GTIN:      product.GTIN,
+		OwnerID:   product.OwnerID,
+		ProductID: product.ID,
+	})
+}
+
+func (s *SmartContract) emitHoldChanged(ctx contractapi.TransactionContextInterface, eventType string, hold *model.Hold) error {
+	return s.emitEvent(ctx, eventType, event.HoldChanged{
+		HoldID:   hold.ID,
+		IssuerID: hold.IssuerID,
+		Reason:   hold.Reason,
+		Scope:    hold.Scope,
+		TargetID: hold.TargetID,
+	})
+}
@@ -0,0 +1,106 @@
+package chaincode
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// GetMyExpiringDrugs groups the caller's stock that expires within the given
+// number of days, or has already expired, by batch. Groups are ordered by expiry
+// date so the most urgent stock comes first.
+func (s *SmartContract) GetMyExpiringDrugs(ctx contractapi.TransactionContextInterface, days int) ([]*model.ExpiringStock, error) {
+	if days < 0 {
+		return nil, fmt.Errorf("days must not be negative")
+	}
+
+	now, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	horizon := now.AddDate(0, 0, days)
+
+	drugs, err := s.getFilteredDrugs(ctx, func(drug *model.Drug, org *model.Organization) bool {
+		return !drug.IsTransferred && !drug.IsDispensed
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stocks := make([]*model.ExpiringStock, 0)
+	stockByBatch := make(map[string]*model.ExpiringStock)
+	batches := make(map[string]*model.Batch)
+	for _, drug := range drugs {
+		batch, err := s.getCachedBatch(ctx, batches, drug.BatchID)
+		if err != nil {
+			return nil, err
+		}
+		if batch.ExpiryDate.IsZero() || batch.ExpiryDate.After(horizon) {
+			continue
+		}
+
+		stock, ok := stockByBatch[batch.ID]
+		if !ok {
+			daysToExpiry := int(math.Floor(batch.ExpiryDate.Sub(now).Hours() / 24))
+			bucket := model.ExpiryHorizonExpired
+			if !isExpired(batch, now) {
+				bucket = model.ExpiryHorizon(daysToExpiry)
+			}
+			stock = &model.ExpiringStock{
+				BatchID:      batch.ID,
+				DaysToExpiry: daysToExpiry,
+				DrugName:     batch.DrugName,
+				DrugsID:      make([]string, 0),
+				ExpiryDate:   batch.ExpiryDate,
+				Horizon:      bucket,
+				LotNumber:    batch.LotNumber,
+			}
+			stockByBatch[batch.ID] = stock
+			stocks = append(stocks, stock)
+		}
+		stock.DrugsID = append(stock.DrugsID, drug.ID)
+	}
+
+	sort.SliceStable(stocks, func(i, j int) bool {
+		return stocks[i].ExpiryDate.Before(stocks[j].ExpiryDate)
+	})
+
+	return stocks, nil
+}
+
+// checkNotExpired compares against the transaction time so every endorser reaches
+// the same result regardless of the client's clock.
+func (s *SmartContract) checkNotExpired(ctx contractapi.TransactionContextInterface, batches map[string]*model.Batch, drug *model.Drug, now time.Time) error {
+	batch, err := s.getCachedBatch(ctx, batches, drug.BatchID)
+	if err != nil {
+		return err
+	}
+	if isExpired(batch, now) {
+		return fmt.Errorf("drug %s expired on %s", drug.ID, batch.ExpiryDate.Format("2006-01-02"))
+	}
+
+	return nil
+}
+
+func (s *SmartContract) getCachedBatch(ctx contractapi.TransactionContextInterface, batches map[string]*model.Batch, id string) (*model.Batch, error) {
+	if batch, ok := batches[id]; ok {
+		return batch, nil
+	}
+
+	batch, err := s.GetBatch(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	batches[id] = batch
+
+	return batch, nil
+}
+
+func isExpired(batch *model.Batch, now time.Time) bool {
+	return !batch.ExpiryDate.IsZero() && !now.Before(batch.ExpiryDate)
+}
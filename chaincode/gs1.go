@@ -0,0 +1,147 @@
+package chaincode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/gs1"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const generatedSerialTxIDLength = 12
+
+func (s *SmartContract) GetDrugBySGTIN(ctx contractapi.TransactionContextInterface, gtin string, serialNumber string) (*model.Drug, error) {
+	gtin, err := gs1.NormalizeGTIN(gtin)
+	if err != nil {
+		return nil, err
+	}
+
+	drugID, err := s.findDrugIDBySGTIN(ctx, gtin, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if drugID == "" {
+		return nil, fmt.Errorf("no drug with GTIN %s and serial number %s", gtin, serialNumber)
+	}
+
+	return s.GetDrug(ctx, drugID)
+}
+
+// ResolveGS1Code resolves a scanned GS1 DataMatrix code to its drug. When the code
+// also carries a lot number or expiry date they must match the drug's batch.
+func (s *SmartContract) ResolveGS1Code(ctx contractapi.TransactionContextInterface, code string) (*model.Drug, error) {
+	sgtin, err := gs1.Parse(code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GS1 code: %w", err)
+	}
+
+	drug, err := s.GetDrugBySGTIN(ctx, sgtin.GTIN, sgtin.Serial)
+	if err != nil {
+		return nil, err
+	}
+
+	if sgtin.LotNumber == "" && sgtin.ExpiryDate == nil {
+		return drug, nil
+	}
+
+	batch, err := s.GetBatch(ctx, drug.BatchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	if sgtin.LotNumber != "" && sgtin.LotNumber != batch.LotNumber {
+		return nil, fmt.Errorf("lot number %s does not match batch lot number %s", sgtin.LotNumber, batch.LotNumber)
+	}
+	if sgtin.ExpiryDate != nil && !sameDate(*sgtin.ExpiryDate, batch.ExpiryDate) {
+		return nil, fmt.Errorf("expiry date %s does not match batch expiry date %s", sgtin.ExpiryDate.Format("2006-01-02"), batch.ExpiryDate.Format("2006-01-02"))
+	}
+
+	return drug, nil
+}
+
+// prepareSerialNumbers normalizes the batch GTIN and returns one serial number per
+// drug, either the ones supplied in the request or serials derived from the
+// transaction ID so every endorser generates the same values.
+func (s *SmartContract) prepareSerialNumbers(ctx contractapi.TransactionContextInterface, createBatch *dto.CreateBatch) ([]string, error) {
+	gtin, err := gs1.NormalizeGTIN(createBatch.GTIN)
+	if err != nil {
+		return nil, err
+	}
+	createBatch.GTIN = gtin
+
+	if err := gs1.ValidateLotNumber(createBatch.LotNumber); err != nil {
+		return nil, err
+	}
+
+	serialNumbers := createBatch.SerialNumbers
+	if len(serialNumbers) == 0 {
+		txID := ctx.GetStub().GetTxID()
+		if len(txID) < generatedSerialTxIDLength {
+			return nil, fmt.Errorf("transaction ID %q is too short to derive serial numbers", txID)
+		}
+
+		serialNumbers = make([]string, createBatch.Amount)
+		for i := range serialNumbers {
+			serialNumbers[i] = fmt.Sprintf("%s%08d", txID[:generatedSerialTxIDLength], i+1)
+		}
+	}
+	if len(serialNumbers) != createBatch.Amount {
+		return nil, fmt.Errorf("got %d serial numbers for %d drugs", len(serialNumbers), createBatch.Amount)
+	}
+
+	seen := make(map[string]bool)
+	for _, serialNumber := range serialNumbers {
+		if err := gs1.ValidateSerial(serialNumber); err != nil {
+			return nil, err
+		}
+		if seen[serialNumber] {
+			return nil, fmt.Errorf("serial number %s is listed more than once", serialNumber)
+		}
+		seen[serialNumber] = true
+
+		drugID, err := s.findDrugIDBySGTIN(ctx, gtin, serialNumber)
+		if err != nil {
+			return nil, err
+		}
+		if drugID != "" {
+			return nil, fmt.Errorf("serial number %s is already assigned to drug %s for GTIN %s", serialNumber, drugID, gtin)
+		}
+	}
+
+	return serialNumbers, nil
+}
+
+func (s *SmartContract) findDrugIDBySGTIN(ctx contractapi.TransactionContextInterface, gtin string, serialNumber string) (string, error) {
+	drugsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(gtinSerialIndex, []string{gtin, serialNumber})
+	if err != nil {
+		return "", fmt.Errorf("failed to get drugs: %w", err)
+	}
+	defer drugsIterator.Close()
+
+	if !drugsIterator.HasNext() {
+		return "", nil
+	}
+
+	responseRange, err := drugsIterator.Next()
+	if err != nil {
+		return "", fmt.Errorf("failed to iterate drugs: %w", err)
+	}
+
+	_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+	if err != nil {
+		return "", fmt.Errorf("failed to split composite key: %w", err)
+	}
+	if len(compositeKeyParts) < 3 {
+		return "", fmt.Errorf("malformed gtin-serial index key %s", responseRange.Key)
+	}
+
+	return compositeKeyParts[2], nil
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
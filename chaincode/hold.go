@@ -0,0 +1,266 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// PlaceHold quarantines stock. Regulators can hold anything; other organizations
+// can hold drugs they own, batches they manufactured, or their own stock.
+func (s *SmartContract) PlaceHold(ctx contractapi.TransactionContextInterface, req string) (*model.Hold, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	var placeHold dto.PlaceHold
+	if err := dto.Decode(req, &placeHold); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkHoldTarget(ctx, org, placeHold.Scope, placeHold.TargetID); err != nil {
+		return nil, err
+	}
+
+	issuedAt, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	holdID, err := s.generateModelId(ctx, holdKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hold := model.Hold{
+		DocType:  model.DocTypeHold,
+		ID:       holdID,
+		IsActive: true,
+		IssuedAt: issuedAt,
+		IssuerID: org.ID,
+		Reason:   placeHold.Reason,
+		Scope:    placeHold.Scope,
+		TargetID: placeHold.TargetID,
+	}
+	if err := s.putHold(ctx, &hold); err != nil {
+		return nil, err
+	}
+
+	activeHoldIndexKey, err := ctx.GetStub().CreateCompositeKey(activeHoldIndex, []string{hold.Scope, hold.TargetID, hold.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %w", err)
+	}
+	if err := ctx.GetStub().PutState(activeHoldIndexKey, []byte{0x00}); err != nil {
+		return nil, fmt.Errorf("failed to put active hold index to world state: %w", err)
+	}
+
+	if err := s.emitHoldChanged(ctx, event.TypeHoldPlaced, &hold); err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+// ReleaseHold lifts a hold. Only the issuer or a regulator can release it.
+func (s *SmartContract) ReleaseHold(ctx contractapi.TransactionContextInterface, holdID string) (*model.Hold, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	hold, err := s.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if org.ID != hold.IssuerID && org.Type != model.OrgTypeRegulator {
+		return nil, fmt.Errorf("only the issuer of hold %s or a regulator can release it", holdID)
+	}
+	if !hold.IsActive {
+		return nil, fmt.Errorf("hold %s has already been released", holdID)
+	}
+
+	releasedAt, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hold.IsActive = false
+	hold.ReleasedAt = releasedAt
+	hold.ReleasedBy = org.ID
+	if err := s.putHold(ctx, hold); err != nil {
+		return nil, err
+	}
+
+	if err := s.delCompositeKey(ctx, activeHoldIndex, hold.Scope, hold.TargetID, hold.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.emitHoldChanged(ctx, event.TypeHoldReleased, hold); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+func (s *SmartContract) GetHold(ctx contractapi.TransactionContextInterface, id string) (*model.Hold, error) {
+	holdJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if holdJSON == nil {
+		return nil, fmt.Errorf("hold %s does not exist", id)
+	}
+
+	var hold model.Hold
+	if err := json.Unmarshal(holdJSON, &hold); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal hold: %w", err)
+	}
+	if hold.DocType != model.DocTypeHold {
+		return nil, fmt.Errorf("hold %s does not exist", id)
+	}
+
+	return &hold, nil
+}
+
+func (s *SmartContract) GetActiveHolds(ctx contractapi.TransactionContextInterface) ([]*model.Hold, error) {
+	holdsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(activeHoldIndex, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holds: %w", err)
+	}
+	defer holdsIterator.Close()
+
+	holds := make([]*model.Hold, 0)
+	for holdsIterator.HasNext() {
+		responseRange, err := holdsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate holds: %w", err)
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %w", err)
+		}
+
+		if len(compositeKeyParts) > 2 {
+			hold, err := s.GetHold(ctx, compositeKeyParts[2])
+			if err != nil {
+				return nil, err
+			}
+			holds = append(holds, hold)
+		}
+	}
+
+	return holds, nil
+}
+
+// findActiveHold returns the ID of a hold that applies to the drug, or "" when the
+// drug is free to move.
+func (s *SmartContract) findActiveHold(ctx contractapi.TransactionContextInterface, drug *model.Drug) (string, error) {
+	targets := [][]string{
+		{model.HoldScopeDrug, drug.ID},
+		{model.HoldScopeBatch, drug.BatchID},
+		{model.HoldScopeOwner, drug.OwnerID},
+	}
+	for _, target := range targets {
+		holdsID, err := s.getActiveHoldIDs(ctx, target[0], target[1])
+		if err != nil {
+			return "", err
+		}
+		if len(holdsID) > 0 {
+			return holdsID[0], nil
+		}
+	}
+
+	return "", nil
+}
+
+// getHeldTargets returns the IDs of everything under an active hold of the scope.
+func (s *SmartContract) getHeldTargets(ctx contractapi.TransactionContextInterface, scope string) (map[string]bool, error) {
+	targetsID, err := s.getIndexedIDs(ctx, activeHoldIndex, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]bool)
+	for _, targetID := range targetsID {
+		targets[targetID] = true
+	}
+	return targets, nil
+}
+
+func (s *SmartContract) getActiveHoldIDs(ctx contractapi.TransactionContextInterface, scope string, targetID string) ([]string, error) {
+	holdsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(activeHoldIndex, []string{scope, targetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holds: %w", err)
+	}
+	defer holdsIterator.Close()
+
+	holdsID := make([]string, 0)
+	for holdsIterator.HasNext() {
+		responseRange, err := holdsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate holds: %w", err)
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %w", err)
+		}
+
+		if len(compositeKeyParts) > 2 {
+			holdsID = append(holdsID, compositeKeyParts[2])
+		}
+	}
+
+	return holdsID, nil
+}
+
+func (s *SmartContract) checkHoldTarget(ctx contractapi.TransactionContextInterface, org *model.Organization, scope string, targetID string) error {
+	isRegulator := org.Type == model.OrgTypeRegulator
+
+	switch scope {
+	case model.HoldScopeDrug:
+		drug, err := s.GetDrug(ctx, targetID)
+		if err != nil {
+			return fmt.Errorf("failed to get drug: %w", err)
+		}
+		if !isRegulator && drug.OwnerID != org.ID {
+			return fmt.Errorf("only the owner of drug %s or a regulator can hold it", targetID)
+		}
+	case model.HoldScopeBatch:
+		batch, err := s.GetBatch(ctx, targetID)
+		if err != nil {
+			return fmt.Errorf("failed to get batch: %w", err)
+		}
+		if !isRegulator && !isBatchManufacturer(batch, org) {
+			return fmt.Errorf("only the manufacturer of batch %s or a regulator can hold it", targetID)
+		}
+	case model.HoldScopeOwner:
+		if _, err := s.GetOrganization(ctx, targetID); err != nil {
+			return err
+		}
+		if !isRegulator && targetID != org.ID {
+			return fmt.Errorf("only a regulator can hold the stock of organization %s", targetID)
+		}
+	}
+
+	return nil
+}
+
+func (s *SmartContract) putHold(ctx contractapi.TransactionContextInterface, hold *model.Hold) error {
+	holdJSON, err := json.Marshal(hold)
+	if err != nil {
+		return fmt.Errorf("failed to marshal hold: %w", err)
+	}
+	if err := ctx.GetStub().PutState(hold.ID, holdJSON); err != nil {
+		return fmt.Errorf("failed to put hold to world state: %w", err)
+	}
+
+	return nil
+}
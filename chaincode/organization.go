@@ -0,0 +1,160 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/cid"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const adminAttribute = "medtrace.admin"
+
+func (s *SmartContract) RegisterOrganization(ctx contractapi.TransactionContextInterface, req string) (*model.Organization, error) {
+	if err := s.assertAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var registerOrganization dto.RegisterOrganization
+	if err := dto.Decode(req, &registerOrganization); err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(registerOrganization.ID, orgKey) || len(registerOrganization.ID) == len(orgKey) {
+		return nil, fmt.Errorf("organization ID %q must start with %q", registerOrganization.ID, orgKey)
+	}
+
+	exists, err := s.organizationExists(ctx, registerOrganization.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("organization %s already exists", registerOrganization.ID)
+	}
+
+	org := model.Organization{
+		ID:       registerOrganization.ID,
+		Location: registerOrganization.Location,
+		Name:     registerOrganization.Name,
+		Status:   model.OrgStatusActive,
+		Type:     registerOrganization.Type,
+	}
+	if err := s.putOrganization(ctx, &org); err != nil {
+		return nil, err
+	}
+
+	if err := s.emitOrganizationChanged(ctx, event.TypeOrganizationRegistered, &org); err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+func (s *SmartContract) UpdateOrganization(ctx contractapi.TransactionContextInterface, id string, req string) (*model.Organization, error) {
+	if err := s.assertAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var updateOrganization dto.UpdateOrganization
+	if err := dto.Decode(req, &updateOrganization); err != nil {
+		return nil, err
+	}
+
+	org, err := s.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	org.Location = updateOrganization.Location
+	org.Name = updateOrganization.Name
+	org.Type = updateOrganization.Type
+
+	if err := s.putOrganization(ctx, org); err != nil {
+		return nil, err
+	}
+
+	if err := s.emitOrganizationChanged(ctx, event.TypeOrganizationUpdated, org); err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func (s *SmartContract) SuspendOrganization(ctx contractapi.TransactionContextInterface, id string) (*model.Organization, error) {
+	if err := s.assertAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	org, err := s.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	if org.Status == model.OrgStatusSuspended {
+		return nil, fmt.Errorf("organization %s is already suspended", id)
+	}
+
+	callerID, err := s.getOrgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if callerID == org.ID {
+		return nil, fmt.Errorf("organization %s cannot suspend itself", id)
+	}
+
+	org.Status = model.OrgStatusSuspended
+	if err := s.putOrganization(ctx, org); err != nil {
+		return nil, err
+	}
+
+	if err := s.emitOrganizationChanged(ctx, event.TypeOrganizationSuspended, org); err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func (s *SmartContract) putOrganization(ctx contractapi.TransactionContextInterface, org *model.Organization) error {
+	org.DocType = model.DocTypeOrganization
+	orgJSON, err := json.Marshal(org)
+	if err != nil {
+		return fmt.Errorf("failed to marshal organization: %w", err)
+	}
+
+	if err := ctx.GetStub().PutState(org.ID, orgJSON); err != nil {
+		return fmt.Errorf("failed to put organization to world state: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SmartContract) organizationExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	orgJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %w", err)
+	}
+
+	return orgJSON != nil, nil
+}
+
+// assertAdmin allows callers whose certificate carries the admin attribute, so the
+// first organizations can be onboarded, and callers belonging to an active regulator.
+func (s *SmartContract) assertAdmin(ctx contractapi.TransactionContextInterface) error {
+	if err := cid.AssertAttributeValue(ctx.GetStub(), adminAttribute, "true"); err == nil {
+		return nil
+	}
+
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get organization ID: %w", err)
+	}
+	if org.Type != model.OrgTypeRegulator {
+		return fmt.Errorf("only administrators or regulators can manage organizations")
+	}
+
+	return nil
+}
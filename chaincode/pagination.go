@@ -0,0 +1,168 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const maxPageSize = 1000
+
+func (s *SmartContract) GetMyDrugWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*model.PaginatedDrugs, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	return s.getDrugsByIndexWithPagination(ctx, ownerDrugIndex, org.ID, pageSize, bookmark)
+}
+
+func (s *SmartContract) GetDrugByBatchWithPagination(ctx contractapi.TransactionContextInterface, batchID string, pageSize int32, bookmark string) (*model.PaginatedDrugs, error) {
+	return s.getDrugsByIndexWithPagination(ctx, batchDrugIndex, batchID, pageSize, bookmark)
+}
+
+func (s *SmartContract) GetDrugByTransferWithPagination(ctx contractapi.TransactionContextInterface, transferID string, pageSize int32, bookmark string) (*model.PaginatedDrugs, error) {
+	return s.getDrugsByIndexWithPagination(ctx, transferDrugIndex, transferID, pageSize, bookmark)
+}
+
+func (s *SmartContract) GetMyOutTransferWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*model.PaginatedTransfers, error) {
+	return s.getMyTransferWithPagination(ctx, false, pageSize, bookmark)
+}
+
+func (s *SmartContract) GetMyInTransferWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*model.PaginatedTransfers, error) {
+	return s.getMyTransferWithPagination(ctx, true, pageSize, bookmark)
+}
+
+func (s *SmartContract) GetAllBatchesWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*model.PaginatedBatches, error) {
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	resIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(batchKey, batchKey+"~", pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batches: %w", err)
+	}
+	defer resIterator.Close()
+
+	batches := make([]*model.Batch, 0)
+	for resIterator.HasNext() {
+		res, err := resIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate batches: %w", err)
+		}
+
+		var batch model.Batch
+		if err := json.Unmarshal(res.Value, &batch); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal batch: %w", err)
+		}
+		batches = append(batches, &batch)
+	}
+
+	return &model.PaginatedBatches{
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+		Records:      batches,
+	}, nil
+}
+
+func (s *SmartContract) getDrugsByIndexWithPagination(ctx contractapi.TransactionContextInterface, index string, attribute string, pageSize int32, bookmark string) (*model.PaginatedDrugs, error) {
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	drugsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(index, []string{attribute}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drugs: %w", err)
+	}
+	defer drugsIterator.Close()
+
+	drugs := make([]*model.Drug, 0)
+	for drugsIterator.HasNext() {
+		responseRange, err := drugsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate drugs: %w", err)
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %w", err)
+		}
+
+		if len(compositeKeyParts) > 1 {
+			drug, err := s.GetDrug(ctx, compositeKeyParts[1])
+			if err != nil {
+				return nil, fmt.Errorf("failed to get drug: %w", err)
+			}
+
+			drugs = append(drugs, drug)
+		}
+	}
+
+	return &model.PaginatedDrugs{
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+		Records:      drugs,
+	}, nil
+}
+
+func (s *SmartContract) getMyTransferWithPagination(ctx contractapi.TransactionContextInterface, isIn bool, pageSize int32, bookmark string) (*model.PaginatedTransfers, error) {
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	var transferIndex string
+	if isIn {
+		transferIndex = receiverTransferIndex
+	} else {
+		transferIndex = senderTransferIndex
+	}
+
+	transfersIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(transferIndex, []string{org.ID}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+	defer transfersIterator.Close()
+
+	transfers := make([]*model.Transfer, 0)
+	for transfersIterator.HasNext() {
+		responseRange, err := transfersIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transfers: %w", err)
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %w", err)
+		}
+
+		if len(compositeKeyParts) > 1 {
+			transfer, err := s.GetTransfer(ctx, compositeKeyParts[1])
+			if err != nil {
+				return nil, fmt.Errorf("failed to get transfer: %w", err)
+			}
+
+			transfers = append(transfers, transfer)
+		}
+	}
+
+	return &model.PaginatedTransfers{
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+		Records:      transfers,
+	}, nil
+}
+
+func validatePageSize(pageSize int32) error {
+	if pageSize < 1 || pageSize > maxPageSize {
+		return fmt.Errorf("page size must be between 1 and %d, got %d", maxPageSize, pageSize)
+	}
+	return nil
+}
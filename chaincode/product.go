@@ -0,0 +1,218 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/gs1"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// RegisterProduct records the master data of a trade item. The registering
+// manufacturer becomes the brand owner and the only organization that can edit
+// the product or create batches of it.
+func (s *SmartContract) RegisterProduct(ctx contractapi.TransactionContextInterface, req string) (*model.Product, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+	if org.Type != model.OrgTypeManufacturer {
+		return nil, fmt.Errorf("only manufacturers can register products")
+	}
+
+	var registerProduct dto.RegisterProduct
+	if err := dto.Decode(req, &registerProduct); err != nil {
+		return nil, err
+	}
+
+	gtin, err := gs1.NormalizeGTIN(registerProduct.GTIN)
+	if err != nil {
+		return nil, err
+	}
+
+	existingID, err := s.findProductIDByGTIN(ctx, gtin)
+	if err != nil {
+		return nil, err
+	}
+	if existingID != "" {
+		return nil, fmt.Errorf("GTIN %s is already registered to product %s", gtin, existingID)
+	}
+
+	productID, err := s.generateModelId(ctx, productKey)
+	if err != nil {
+		return nil, err
+	}
+
+	product := model.Product{
+		DocType:                      model.DocTypeProduct,
+		DosageForm:                   registerProduct.DosageForm,
+		GenericName:                  registerProduct.GenericName,
+		GTIN:                         gtin,
+		ID:                           productID,
+		MarketingAuthorizationNumber: registerProduct.MarketingAuthorizationNumber,
+		OwnerID:                      org.ID,
+		PackSize:                     registerProduct.PackSize,
+		Strength:                     registerProduct.Strength,
+	}
+	if err := s.putProduct(ctx, &product); err != nil {
+		return nil, err
+	}
+
+	gtinProductIndexKey, err := ctx.GetStub().CreateCompositeKey(gtinProductIndex, []string{gtin, product.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %w", err)
+	}
+	if err := ctx.GetStub().PutState(gtinProductIndexKey, []byte{0x00}); err != nil {
+		return nil, fmt.Errorf("failed to put gtin-product index to world state: %w", err)
+	}
+
+	if err := s.emitProductChanged(ctx, event.TypeProductRegistered, &product); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// UpdateProduct lets the brand owner correct product master data. The GTIN
+// identifies the trade item and cannot change.
+func (s *SmartContract) UpdateProduct(ctx contractapi.TransactionContextInterface, productID string, req string) (*model.Product, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	product, err := s.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product.OwnerID != org.ID {
+		return nil, fmt.Errorf("only the brand owner of product %s can update it", productID)
+	}
+
+	var updateProduct dto.UpdateProduct
+	if err := dto.Decode(req, &updateProduct); err != nil {
+		return nil, err
+	}
+
+	product.DosageForm = updateProduct.DosageForm
+	product.GenericName = updateProduct.GenericName
+	product.MarketingAuthorizationNumber = updateProduct.MarketingAuthorizationNumber
+	product.PackSize = updateProduct.PackSize
+	product.Strength = updateProduct.Strength
+	if err := s.putProduct(ctx, product); err != nil {
+		return nil, err
+	}
+
+	if err := s.emitProductChanged(ctx, event.TypeProductUpdated, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (s *SmartContract) GetProduct(ctx contractapi.TransactionContextInterface, id string) (*model.Product, error) {
+	productJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if productJSON == nil {
+		return nil, fmt.Errorf("product %s does not exist", id)
+	}
+
+	var product model.Product
+	if err := json.Unmarshal(productJSON, &product); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal product: %w", err)
+	}
+	if product.DocType != model.DocTypeProduct {
+		return nil, fmt.Errorf("product %s does not exist", id)
+	}
+
+	return &product, nil
+}
+
+func (s *SmartContract) GetProductByGTIN(ctx contractapi.TransactionContextInterface, gtin string) (*model.Product, error) {
+	gtin, err := gs1.NormalizeGTIN(gtin)
+	if err != nil {
+		return nil, err
+	}
+
+	productID, err := s.findProductIDByGTIN(ctx, gtin)
+	if err != nil {
+		return nil, err
+	}
+	if productID == "" {
+		return nil, fmt.Errorf("no product with GTIN %s", gtin)
+	}
+
+	return s.GetProduct(ctx, productID)
+}
+
+func (s *SmartContract) GetAllProducts(ctx contractapi.TransactionContextInterface) ([]*model.Product, error) {
+	resIterator, err := ctx.GetStub().GetStateByRange(productKey, productKey+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	defer resIterator.Close()
+
+	products := make([]*model.Product, 0)
+	for resIterator.HasNext() {
+		res, err := resIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate products: %w", err)
+		}
+
+		var product model.Product
+		if err := json.Unmarshal(res.Value, &product); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal product: %w", err)
+		}
+		products = append(products, &product)
+	}
+
+	return products, nil
+}
+
+func (s *SmartContract) GetBatchesByProduct(ctx contractapi.TransactionContextInterface, productID string) ([]*model.Batch, error) {
+	batchesID, err := s.getIndexedIDs(ctx, productBatchIndex, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	batches := make([]*model.Batch, 0, len(batchesID))
+	for _, batchID := range batchesID {
+		batch, err := s.GetBatch(ctx, batchID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get batch: %w", err)
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, nil
+}
+
+func (s *SmartContract) putProduct(ctx contractapi.TransactionContextInterface, product *model.Product) error {
+	productJSON, err := json.Marshal(product)
+	if err != nil {
+		return fmt.Errorf("failed to marshal product: %w", err)
+	}
+	if err := ctx.GetStub().PutState(product.ID, productJSON); err != nil {
+		return fmt.Errorf("failed to put product to world state: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SmartContract) findProductIDByGTIN(ctx contractapi.TransactionContextInterface, gtin string) (string, error) {
+	productsID, err := s.getIndexedIDs(ctx, gtinProductIndex, gtin)
+	if err != nil {
+		return "", err
+	}
+	if len(productsID) == 0 {
+		return "", nil
+	}
+
+	return productsID[0], nil
+}
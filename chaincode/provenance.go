@@ -0,0 +1,155 @@
+package chaincode
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func (s *SmartContract) GetDrugProvenance(ctx contractapi.TransactionContextInterface, drugID string) (*model.DrugProvenance, error) {
+	drug, err := s.GetDrug(ctx, drugID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drug: %w", err)
+	}
+
+	batch, err := s.GetBatch(ctx, drug.BatchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	transferIDs, err := s.getDrugTransferIDs(ctx, drug.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Drugs transferred before the drug~transfer index existed only remember their last transfer.
+	if len(transferIDs) == 0 && drug.TransferID != "" {
+		transferIDs = append(transferIDs, drug.TransferID)
+	}
+
+	orgs := make(map[string]*model.Organization)
+	hops := make([]*model.CustodyHop, 0, len(transferIDs))
+	for _, transferID := range transferIDs {
+		transfer, err := s.GetTransfer(ctx, transferID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transfer: %w", err)
+		}
+		if !isDrugReceived(transfer, drug.ID) {
+			continue
+		}
+
+		sender, err := s.getCachedOrganization(ctx, orgs, transfer.SenderID)
+		if err != nil {
+			return nil, err
+		}
+		receiver, err := s.getCachedOrganization(ctx, orgs, transfer.ReceiverID)
+		if err != nil {
+			return nil, err
+		}
+
+		hops = append(hops, &model.CustodyHop{
+			Event:            model.CustodyEventTransferred,
+			ReceiveDate:      transfer.ReceiveDate,
+			ReceiverID:       transfer.ReceiverID,
+			ReceiverLocation: receiver.Location,
+			SenderID:         transfer.SenderID,
+			SenderLocation:   sender.Location,
+			TransferDate:     transfer.TransferDate,
+			TransferID:       transfer.ID,
+		})
+	}
+	sort.SliceStable(hops, func(i, j int) bool {
+		return hops[i].ReceiveDate.Before(hops[j].ReceiveDate)
+	})
+
+	if drug.DispenseID != "" {
+		dispense, err := s.GetDispense(ctx, drug.DispenseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dispense: %w", err)
+		}
+		pharmacy, err := s.getCachedOrganization(ctx, orgs, dispense.PharmacyID)
+		if err != nil {
+			return nil, err
+		}
+
+		hops = append(hops, &model.CustodyHop{
+			Event:          model.CustodyEventDispensed,
+			ReceiveDate:    dispense.DispensedAt,
+			SenderID:       dispense.PharmacyID,
+			SenderLocation: pharmacy.Location,
+			TransferDate:   dispense.DispensedAt,
+			TransferID:     dispense.ID,
+		})
+	}
+
+	manufactured := &model.CustodyHop{
+		Event:            model.CustodyEventManufactured,
+		ReceiveDate:      batch.ProductionDate,
+		ReceiverID:       batch.ManufacturerID,
+		ReceiverLocation: batch.ManufactureLocation,
+		TransferDate:     batch.ProductionDate,
+	}
+
+	return &model.DrugProvenance{
+		Batch:  batch,
+		DrugID: drug.ID,
+		Hops:   append([]*model.CustodyHop{manufactured}, hops...),
+	}, nil
+}
+
+func (s *SmartContract) getDrugTransferIDs(ctx contractapi.TransactionContextInterface, drugID string) ([]string, error) {
+	transfersIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(drugTransferIndex, []string{drugID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drug transfers: %w", err)
+	}
+	defer transfersIterator.Close()
+
+	transferIDs := make([]string, 0)
+	for transfersIterator.HasNext() {
+		responseRange, err := transfersIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate drug transfers: %w", err)
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %w", err)
+		}
+
+		if len(compositeKeyParts) > 1 {
+			transferIDs = append(transferIDs, compositeKeyParts[1])
+		}
+	}
+
+	return transferIDs, nil
+}
+
+func (s *SmartContract) getCachedOrganization(ctx contractapi.TransactionContextInterface, orgs map[string]*model.Organization, id string) (*model.Organization, error) {
+	if org, ok := orgs[id]; ok {
+		return org, nil
+	}
+
+	org, err := s.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	orgs[id] = org
+
+	return org, nil
+}
+
+func isDrugReceived(transfer *model.Transfer, drugID string) bool {
+	switch transfer.Status {
+	case model.TransferStatusAccepted:
+		return true
+	case model.TransferStatusPartiallyAccepted:
+		for _, outcome := range transfer.DrugOutcomes {
+			if outcome.DrugID == drugID {
+				return outcome.Outcome == model.DrugOutcomeReceived
+			}
+		}
+	}
+	return false
+}
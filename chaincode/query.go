@@ -0,0 +1,321 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+type selector map[string]interface{}
+
+func (s *SmartContract) QueryDrugs(ctx contractapi.TransactionContextInterface, req string, pageSize int32, bookmark string) (*model.PaginatedDrugs, error) {
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	var queryDrugs dto.QueryDrugs
+	if err := dto.Decode(req, &queryDrugs); err != nil {
+		return nil, err
+	}
+
+	// Regulators oversee every owner's stock and may leave the owner out to search
+	// the whole ledger; everyone else only sees their own drugs.
+	isRegulator := org.Type == model.OrgTypeRegulator
+	if queryDrugs.OwnerID == "" && !isRegulator {
+		queryDrugs.OwnerID = org.ID
+	}
+	if queryDrugs.OwnerID != org.ID && !isRegulator {
+		return nil, fmt.Errorf("organization %s cannot query drugs owned by %s", org.ID, queryDrugs.OwnerID)
+	}
+
+	query := selector{"$and": []selector{matchDocType(model.DocTypeDrug, drugKey)}}
+	if queryDrugs.OwnerID != "" {
+		query["OwnerID"] = queryDrugs.OwnerID
+	}
+	if queryDrugs.IsRecalled != nil {
+		query["isRecalled"] = *queryDrugs.IsRecalled
+	}
+	if queryDrugs.IsTransferred != nil {
+		query["isTransferred"] = *queryDrugs.IsTransferred
+	}
+
+	// Drug name and expiry live on the batch, so they are resolved to batch IDs first.
+	batchQuery := selector{"$and": []selector{matchDocType(model.DocTypeBatch, batchKey)}}
+	if queryDrugs.DrugName != "" {
+		batchQuery["DrugName"] = queryDrugs.DrugName
+	}
+	addDateRange(batchQuery, "ExpiryDate", queryDrugs.ExpiryFrom, queryDrugs.ExpiryTo)
+
+	if len(batchQuery) > 1 {
+		batchIDs, err := s.queryBatchIDs(ctx, batchQuery)
+		if err != nil {
+			return nil, err
+		}
+		if queryDrugs.BatchID != "" {
+			batchIDs = keepBatchID(batchIDs, queryDrugs.BatchID)
+		}
+		query["BatchID"] = selector{"$in": batchIDs}
+	} else if queryDrugs.BatchID != "" {
+		query["BatchID"] = queryDrugs.BatchID
+	}
+
+	resIterator, nextBookmark, fetchedCount, err := s.runQueryWithPagination(ctx, query, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resIterator.Close()
+
+	drugs := make([]*model.Drug, 0)
+	for resIterator.HasNext() {
+		res, err := resIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate drugs: %w", err)
+		}
+
+		var drug model.Drug
+		if err := json.Unmarshal(res.Value, &drug); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal drug: %w", err)
+		}
+		drugs = append(drugs, &drug)
+	}
+
+	return &model.PaginatedDrugs{
+		Bookmark:     nextBookmark,
+		FetchedCount: fetchedCount,
+		Records:      drugs,
+	}, nil
+}
+
+func (s *SmartContract) QueryBatches(ctx contractapi.TransactionContextInterface, req string, pageSize int32, bookmark string) (*model.PaginatedBatches, error) {
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	var queryBatches dto.QueryBatches
+	if err := dto.Decode(req, &queryBatches); err != nil {
+		return nil, err
+	}
+
+	query := selector{"$and": []selector{matchDocType(model.DocTypeBatch, batchKey)}}
+	if queryBatches.DrugName != "" {
+		query["DrugName"] = queryBatches.DrugName
+	}
+	if queryBatches.ManufacturerID != "" {
+		query["ManufacturerID"] = queryBatches.ManufacturerID
+	}
+	if queryBatches.IsRecalled != nil {
+		query["isRecalled"] = *queryBatches.IsRecalled
+	}
+	addDateRange(query, "ExpiryDate", queryBatches.ExpiryFrom, queryBatches.ExpiryTo)
+	addDateRange(query, "ProductionDate", queryBatches.ProductionFrom, queryBatches.ProductionTo)
+
+	resIterator, nextBookmark, fetchedCount, err := s.runQueryWithPagination(ctx, query, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resIterator.Close()
+
+	batches := make([]*model.Batch, 0)
+	for resIterator.HasNext() {
+		res, err := resIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate batches: %w", err)
+		}
+
+		var batch model.Batch
+		if err := json.Unmarshal(res.Value, &batch); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal batch: %w", err)
+		}
+		batches = append(batches, &batch)
+	}
+
+	return &model.PaginatedBatches{
+		Bookmark:     nextBookmark,
+		FetchedCount: fetchedCount,
+		Records:      batches,
+	}, nil
+}
+
+func (s *SmartContract) QueryTransfers(ctx contractapi.TransactionContextInterface, req string, pageSize int32, bookmark string) (*model.PaginatedTransfers, error) {
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	var queryTransfers dto.QueryTransfers
+	if err := dto.Decode(req, &queryTransfers); err != nil {
+		return nil, err
+	}
+
+	conditions := []selector{matchDocType(model.DocTypeTransfer, transferKey)}
+	if org.Type != model.OrgTypeRegulator {
+		conditions = append(conditions, selector{"$or": []selector{
+			{"SenderID": org.ID},
+			{"ReceiverID": org.ID},
+		}})
+	}
+	if queryTransfers.Status != "" {
+		conditions = append(conditions, matchTransferStatus(queryTransfers.Status))
+	}
+
+	query := selector{"$and": conditions}
+	if queryTransfers.SenderID != "" {
+		query["SenderID"] = queryTransfers.SenderID
+	}
+	if queryTransfers.ReceiverID != "" {
+		query["ReceiverID"] = queryTransfers.ReceiverID
+	}
+	addDateRange(query, "TransferDate", queryTransfers.TransferFrom, queryTransfers.TransferTo)
+
+	resIterator, nextBookmark, fetchedCount, err := s.runQueryWithPagination(ctx, query, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resIterator.Close()
+
+	transfers := make([]*model.Transfer, 0)
+	for resIterator.HasNext() {
+		res, err := resIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transfers: %w", err)
+		}
+
+		var transfer model.Transfer
+		if err := json.Unmarshal(res.Value, &transfer); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transfer: %w", err)
+		}
+		if transfer.Status == "" {
+			status, err := legacyTransferStatus(res.Value, &transfer)
+			if err != nil {
+				return nil, err
+			}
+			transfer.Status = status
+		}
+		transfers = append(transfers, &transfer)
+	}
+
+	return &model.PaginatedTransfers{
+		Bookmark:     nextBookmark,
+		FetchedCount: fetchedCount,
+		Records:      transfers,
+	}, nil
+}
+
+func (s *SmartContract) runQueryWithPagination(ctx contractapi.TransactionContextInterface, query selector, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, string, int32, error) {
+	queryJSON, err := json.Marshal(map[string]interface{}{"selector": query})
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	resIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(string(queryJSON), pageSize, bookmark)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("failed to run query: %w", err)
+	}
+
+	return resIterator, metadata.GetBookmark(), metadata.GetFetchedRecordsCount(), nil
+}
+
+func (s *SmartContract) queryBatchIDs(ctx contractapi.TransactionContextInterface, query selector) ([]string, error) {
+	queryJSON, err := json.Marshal(map[string]interface{}{
+		"selector": query,
+		"fields":   []string{"ID"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	resIterator, err := ctx.GetStub().GetQueryResult(string(queryJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	defer resIterator.Close()
+
+	batchIDs := make([]string, 0)
+	for resIterator.HasNext() {
+		res, err := resIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate batches: %w", err)
+		}
+		batchIDs = append(batchIDs, res.Key)
+	}
+
+	return batchIDs, nil
+}
+
+// matchDocType selects documents of one type. Records written before docType was
+// stored are matched by their key prefix instead.
+func matchDocType(docType string, keyPrefix string) selector {
+	return selector{"$or": []selector{
+		{"docType": docType},
+		{
+			"docType": selector{"$exists": false},
+			"_id":     selector{"$regex": "^" + keyPrefix},
+		},
+	}}
+}
+
+// matchTransferStatus selects transfers by status, including legacy transfers
+// whose status legacyTransferStatus derives from isAccepted and ReceiveDate.
+func matchTransferStatus(status string) selector {
+	zeroReceiveDate := selector{"$or": []selector{
+		{"ReceiveDate": selector{"$exists": false}},
+		{"ReceiveDate": time.Time{}.Format(time.RFC3339Nano)},
+	}}
+	notAccepted := selector{"isAccepted": selector{"$ne": true}}
+
+	var legacy selector
+	switch status {
+	case model.TransferStatusAccepted:
+		legacy = selector{"isAccepted": true}
+	case model.TransferStatusPending:
+		legacy = selector{"$and": []selector{notAccepted, zeroReceiveDate}}
+	case model.TransferStatusRejected:
+		legacy = selector{"$and": []selector{notAccepted, {"$nor": []selector{zeroReceiveDate}}}}
+	default:
+		return selector{"Status": status}
+	}
+
+	return selector{"$or": []selector{
+		{"Status": status},
+		{"$and": []selector{{"Status": selector{"$exists": false}}, legacy}},
+	}}
+}
+
+// addDateRange filters on a date field. CouchDB compares the stored strings, so
+// the bounds are formatted the way encoding/json stores a UTC time.Time.
+func addDateRange(query selector, field string, from *time.Time, to *time.Time) {
+	dateRange := selector{}
+	if from != nil {
+		dateRange["$gte"] = from.UTC().Format(time.RFC3339Nano)
+	}
+	if to != nil {
+		dateRange["$lte"] = to.UTC().Format(time.RFC3339Nano)
+	}
+	if len(dateRange) > 0 {
+		query[field] = dateRange
+	}
+}
+
+func keepBatchID(batchIDs []string, batchID string) []string {
+	for _, candidate := range batchIDs {
+		if candidate == batchID {
+			return []string{batchID}
+		}
+	}
+	return []string{}
+}
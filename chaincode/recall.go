@@ -0,0 +1,174 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+func (s *SmartContract) RecallBatch(ctx contractapi.TransactionContextInterface, batchID string, reason string, severity string) (*model.Batch, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	if !model.IsValidRecallSeverity(severity) {
+		return nil, fmt.Errorf("invalid recall severity %q", severity)
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("recall reason must not be empty")
+	}
+
+	batch, err := s.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	if !isBatchManufacturer(batch, org) && org.Type != model.OrgTypeRegulator {
+		return nil, fmt.Errorf("only the manufacturer of batch %s or a regulator can recall it", batchID)
+	}
+	if batch.IsRecalled {
+		return nil, fmt.Errorf("batch %s has already been recalled", batchID)
+	}
+
+	recallDate, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batch.IsRecalled = true
+	batch.RecallDate = recallDate
+	batch.RecallReason = reason
+	batch.RecallSeverity = severity
+	batch.RecalledBy = org.ID
+
+	batchJSON, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	if err := ctx.GetStub().PutState(batch.ID, batchJSON); err != nil {
+		return nil, fmt.Errorf("failed to put batch to world state: %w", err)
+	}
+
+	drugs, err := s.GetDrugByBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch drugs: %w", err)
+	}
+
+	var drugsIDs []string
+	for _, drug := range drugs {
+		drug.IsRecalled = true
+
+		drugJSON, err := json.Marshal(drug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal drug: %w", err)
+		}
+		if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+			return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+		}
+
+		drugsIDs = append(drugsIDs, drug.ID)
+	}
+	log.Printf("Drugs recalled: %v\n", drugsIDs)
+
+	if err := s.emitEvent(ctx, event.TypeBatchRecalled, event.BatchRecalled{
+		BatchID:        batch.ID,
+		RecallReason:   batch.RecallReason,
+		RecallSeverity: batch.RecallSeverity,
+		RecalledBy:     batch.RecalledBy,
+		RecalledUnits:  len(drugsIDs),
+	}); err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+func (s *SmartContract) GetRecallStatus(ctx contractapi.TransactionContextInterface, batchID string) (*model.RecallStatus, error) {
+	batch, err := s.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	drugs, err := s.GetDrugByBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch drugs: %w", err)
+	}
+
+	recalledUnits := 0
+	for _, drug := range drugs {
+		if drug.IsRecalled {
+			recalledUnits++
+		}
+	}
+
+	return &model.RecallStatus{
+		BatchID:        batch.ID,
+		IsRecalled:     batch.IsRecalled,
+		RecallDate:     batch.RecallDate,
+		RecallReason:   batch.RecallReason,
+		RecallSeverity: batch.RecallSeverity,
+		RecalledBy:     batch.RecalledBy,
+		RecalledUnits:  recalledUnits,
+		TotalUnits:     len(drugs),
+	}, nil
+}
+
+func (s *SmartContract) GetRecallHolders(ctx contractapi.TransactionContextInterface, batchID string) ([]*model.RecallHolder, error) {
+	batch, err := s.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	if !batch.IsRecalled {
+		return nil, fmt.Errorf("batch %s has not been recalled", batchID)
+	}
+
+	drugs, err := s.GetDrugByBatch(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch drugs: %w", err)
+	}
+
+	holders := make([]*model.RecallHolder, 0)
+	holderByOrg := make(map[string]*model.RecallHolder)
+	for _, drug := range drugs {
+		ownerDrugIndexKey, err := ctx.GetStub().CreateCompositeKey(ownerDrugIndex, []string{drug.OwnerID, drug.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %w", err)
+		}
+		held, err := ctx.GetStub().GetState(ownerDrugIndexKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read owner-drug index from world state: %w", err)
+		}
+		if held == nil {
+			continue
+		}
+
+		holder, ok := holderByOrg[drug.OwnerID]
+		if !ok {
+			holder = &model.RecallHolder{
+				DrugsID:        make([]string, 0),
+				OrganizationID: drug.OwnerID,
+			}
+			holderByOrg[drug.OwnerID] = holder
+			holders = append(holders, holder)
+		}
+
+		holder.DrugsID = append(holder.DrugsID, drug.ID)
+		if drug.IsTransferred {
+			holder.InTransit++
+		}
+	}
+
+	return holders, nil
+}
+
+func isBatchManufacturer(batch *model.Batch, org *model.Organization) bool {
+	if batch.ManufacturerID != "" {
+		return batch.ManufacturerID == org.ID
+	}
+	return org.Type == model.OrgTypeManufacturer && batch.ManufacturerName == org.Name
+}
@@ -0,0 +1,74 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// GetDrugsByOwnerWithPagination lets a regulator inspect the stock of any
+// organization. Members use GetMyDrugWithPagination for their own stock.
+func (s *SmartContract) GetDrugsByOwnerWithPagination(ctx contractapi.TransactionContextInterface, ownerID string, pageSize int32, bookmark string) (*model.PaginatedDrugs, error) {
+	if _, err := s.assertRegulator(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.getDrugsByIndexWithPagination(ctx, ownerDrugIndex, ownerID, pageSize, bookmark)
+}
+
+func (s *SmartContract) GetAllTransfersWithPagination(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string) (*model.PaginatedTransfers, error) {
+	if _, err := s.assertRegulator(ctx); err != nil {
+		return nil, err
+	}
+	if err := validatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+
+	resIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(transferKey, transferKey+"~", pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfers: %w", err)
+	}
+	defer resIterator.Close()
+
+	transfers := make([]*model.Transfer, 0)
+	for resIterator.HasNext() {
+		res, err := resIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate transfers: %w", err)
+		}
+
+		var transfer model.Transfer
+		if err := json.Unmarshal(res.Value, &transfer); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal transfer: %w", err)
+		}
+		if transfer.Status == "" {
+			status, err := legacyTransferStatus(res.Value, &transfer)
+			if err != nil {
+				return nil, err
+			}
+			transfer.Status = status
+		}
+		transfers = append(transfers, &transfer)
+	}
+
+	return &model.PaginatedTransfers{
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+		Records:      transfers,
+	}, nil
+}
+
+func (s *SmartContract) assertRegulator(ctx contractapi.TransactionContextInterface) (*model.Organization, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+	if org.Type != model.OrgTypeRegulator {
+		return nil, fmt.Errorf("only regulators can perform this operation")
+	}
+
+	return org, nil
+}
@@ -0,0 +1,78 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// CreateReturn sends drugs back to the partner that supplied them. Every drug must
+// have been received by the caller in the original forward transfer and still be
+// held from that receipt. The receiver accepts or rejects the return like any
+// other transfer; accepted non-saleable units cannot be sold on.
+func (s *SmartContract) CreateReturn(ctx contractapi.TransactionContextInterface, req string) (*model.Transfer, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var createReturn dto.CreateReturn
+	if err := dto.Decode(req, &createReturn); err != nil {
+		return nil, err
+	}
+
+	original, err := s.GetTransfer(ctx, createReturn.OriginalTransferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get original transfer: %w", err)
+	}
+	if original.ReceiverID != org.ID {
+		return nil, fmt.Errorf("transfer %s was not received by %s", original.ID, org.ID)
+	}
+	if original.Return != nil {
+		return nil, fmt.Errorf("transfer %s is itself a return and cannot be returned", original.ID)
+	}
+
+	if _, err := s.checkTransferRoute(ctx, org, original.SenderID, true); err != nil {
+		return nil, err
+	}
+
+	originalDrugsID, err := s.getIndexedIDs(ctx, transferDrugIndex, original.ID)
+	if err != nil {
+		return nil, err
+	}
+	inOriginal := make(map[string]bool)
+	for _, drugID := range originalDrugsID {
+		inOriginal[drugID] = true
+	}
+
+	for _, drugID := range createReturn.DrugsID {
+		if !inOriginal[drugID] {
+			return nil, fmt.Errorf("drug %s was not part of transfer %s", drugID, original.ID)
+		}
+		if !isDrugReceived(original, drugID) {
+			return nil, fmt.Errorf("drug %s was not received in transfer %s", drugID, original.ID)
+		}
+
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, err
+		}
+		if drug.TransferID != original.ID {
+			return nil, fmt.Errorf("drug %s has moved since transfer %s", drugID, original.ID)
+		}
+	}
+
+	transfer := model.Transfer{
+		DeclaredTransferDate: createReturn.TransferDate,
+		ReceiverID:           original.SenderID,
+		Return: &model.TransferReturn{
+			Disposition:        createReturn.Disposition,
+			OriginalTransferID: original.ID,
+			ReasonCode:         createReturn.ReasonCode,
+		},
+	}
+	return s.createTransfer(ctx, org, &transfer, createReturn.DrugsID, nil)
+}
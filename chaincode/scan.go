@@ -0,0 +1,318 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	// A genuine pack cannot be scanned this far apart within the window, so the
+	// second scan is taken as a sign that the serial number was cloned.
+	distantScanWindow     = 6 * time.Hour
+	distantScanDistanceKm = 100.0
+
+	earthRadiusKm = 6371.0
+)
+
+// RecordScan logs a scan of a drug by the calling organization and raises alerts
+// for patterns that suggest a counterfeit or cloned pack. Alerts are kept on the
+// drug until an administrator or regulator clears them.
+func (s *SmartContract) RecordScan(ctx contractapi.TransactionContextInterface, req string) (*model.Scan, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	var recordScan dto.RecordScan
+	if err := dto.Decode(req, &recordScan); err != nil {
+		return nil, err
+	}
+
+	drug, err := s.GetDrug(ctx, recordScan.DrugID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drug: %w", err)
+	}
+
+	scannedAt, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scanID, err := s.generateModelId(ctx, scanKey)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := model.Scan{
+		DocType:   model.DocTypeScan,
+		DrugID:    drug.ID,
+		ID:        scanID,
+		Latitude:  recordScan.Latitude,
+		Location:  recordScan.Location,
+		Longitude: recordScan.Longitude,
+		ScannedAt: scannedAt,
+		ScannerID: org.ID,
+	}
+
+	scan.Alerts, err = s.detectScanAlerts(ctx, drug, org, &scan)
+	if err != nil {
+		return nil, err
+	}
+
+	scanJSON, err := json.Marshal(scan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scan: %w", err)
+	}
+	if err := ctx.GetStub().PutState(scan.ID, scanJSON); err != nil {
+		return nil, fmt.Errorf("failed to put scan to world state: %w", err)
+	}
+
+	drugScanIndexKey, err := ctx.GetStub().CreateCompositeKey(drugScanIndex, []string{drug.ID, scan.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %w", err)
+	}
+	if err := ctx.GetStub().PutState(drugScanIndexKey, []byte{0x00}); err != nil {
+		return nil, fmt.Errorf("failed to put drug-scan index to world state: %w", err)
+	}
+
+	scanned, err := event.New(event.TypeDrugScanned, event.DrugScanned{
+		Alerts:    scan.Alerts,
+		DrugID:    drug.ID,
+		ScanID:    scan.ID,
+		ScannerID: org.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := []*event.Event{scanned}
+
+	if addDrugAlerts(drug, scan.Alerts) {
+		drugJSON, err := json.Marshal(drug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal drug: %w", err)
+		}
+		if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+			return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+		}
+
+		flagged, err := event.New(event.TypeDrugFlagged, event.DrugAlert{
+			Alerts:  drug.Alerts,
+			DrugID:  drug.ID,
+			OwnerID: drug.OwnerID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, flagged)
+	}
+
+	if err := s.emitEvents(ctx, events...); err != nil {
+		return nil, err
+	}
+
+	return &scan, nil
+}
+
+// ClearDrugAlerts lifts the alert state of a drug once its scans have been
+// investigated. The scans themselves stay on the ledger.
+func (s *SmartContract) ClearDrugAlerts(ctx contractapi.TransactionContextInterface, drugID string) (*model.Drug, error) {
+	if err := s.assertAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	drug, err := s.GetDrug(ctx, drugID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drug: %w", err)
+	}
+	if !drug.IsFlagged {
+		return nil, fmt.Errorf("drug %s has no alerts", drugID)
+	}
+
+	clearedAlerts := drug.Alerts
+	drug.Alerts = nil
+	drug.IsFlagged = false
+
+	drugJSON, err := json.Marshal(drug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal drug: %w", err)
+	}
+	if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+		return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+	}
+
+	if err := s.emitEvent(ctx, event.TypeDrugAlertsCleared, event.DrugAlert{
+		Alerts:  clearedAlerts,
+		DrugID:  drug.ID,
+		OwnerID: drug.OwnerID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return drug, nil
+}
+
+func (s *SmartContract) GetDrugScans(ctx contractapi.TransactionContextInterface, drugID string) ([]*model.Scan, error) {
+	scansIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(drugScanIndex, []string{drugID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scans: %w", err)
+	}
+	defer scansIterator.Close()
+
+	scans := make([]*model.Scan, 0)
+	for scansIterator.HasNext() {
+		responseRange, err := scansIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate scans: %w", err)
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %w", err)
+		}
+
+		if len(compositeKeyParts) > 1 {
+			scan, err := s.GetScan(ctx, compositeKeyParts[1])
+			if err != nil {
+				return nil, fmt.Errorf("failed to get scan: %w", err)
+			}
+
+			scans = append(scans, scan)
+		}
+	}
+
+	return scans, nil
+}
+
+func (s *SmartContract) GetScan(ctx contractapi.TransactionContextInterface, id string) (*model.Scan, error) {
+	scanJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if scanJSON == nil {
+		return nil, fmt.Errorf("scan %s does not exist", id)
+	}
+
+	var scan model.Scan
+	if err := json.Unmarshal(scanJSON, &scan); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal scan: %w", err)
+	}
+
+	return &scan, nil
+}
+
+// detectScanAlerts applies the anomaly rules to a new scan. Regulators inspect stock
+// they do not own, and the receiver of a pending transfer scans goods on arrival,
+// so neither counts as a scan by a non-owner.
+func (s *SmartContract) detectScanAlerts(ctx contractapi.TransactionContextInterface, drug *model.Drug, org *model.Organization, scan *model.Scan) ([]string, error) {
+	alerts := make([]string, 0)
+
+	dispensed, err := s.isDispensed(ctx, drug)
+	if err != nil {
+		return nil, err
+	}
+	if dispensed {
+		alerts = append(alerts, model.ScanAlertAfterDispensing)
+	}
+	if drug.IsDecommissioned {
+		alerts = append(alerts, model.ScanAlertDecommissioned)
+	}
+
+	if org.ID != drug.OwnerID && org.Type != model.OrgTypeRegulator {
+		receiving, err := s.isPendingReceiver(ctx, drug, org.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !receiving {
+			alerts = append(alerts, model.ScanAlertNotOwner)
+		}
+	}
+
+	if scan.Latitude != nil && scan.Longitude != nil {
+		previousScans, err := s.GetDrugScans(ctx, drug.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, previous := range previousScans {
+			if previous.Latitude == nil || previous.Longitude == nil {
+				continue
+			}
+			if scan.ScannedAt.Sub(previous.ScannedAt) > distantScanWindow {
+				continue
+			}
+			if distanceKm(*previous.Latitude, *previous.Longitude, *scan.Latitude, *scan.Longitude) > distantScanDistanceKm {
+				alerts = append(alerts, model.ScanAlertDistantScan)
+				break
+			}
+		}
+	}
+
+	return alerts, nil
+}
+
+func (s *SmartContract) isPendingReceiver(ctx contractapi.TransactionContextInterface, drug *model.Drug, orgID string) (bool, error) {
+	if !drug.IsTransferred {
+		return false, nil
+	}
+
+	transferIDs, err := s.getDrugTransferIDs(ctx, drug.ID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, transferID := range transferIDs {
+		transfer, err := s.GetTransfer(ctx, transferID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get transfer: %w", err)
+		}
+		if transfer.Status == model.TransferStatusPending && transfer.ReceiverID == orgID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// addDrugAlerts merges alerts into the drug and reports whether any were new.
+func addDrugAlerts(drug *model.Drug, alerts []string) bool {
+	added := false
+	for _, alert := range alerts {
+		known := false
+		for _, existing := range drug.Alerts {
+			if existing == alert {
+				known = true
+				break
+			}
+		}
+		if !known {
+			drug.Alerts = append(drug.Alerts, alert)
+			added = true
+		}
+	}
+
+	if added {
+		drug.IsFlagged = true
+	}
+	return added
+}
+
+// distanceKm returns the great-circle distance between two points using the
+// haversine formula.
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
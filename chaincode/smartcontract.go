@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
 	"github.com/AryaJayadi/MedTrace_chaincode/model"
 
 	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/cid"
@@ -20,14 +21,33 @@ const (
 	senderTransferIndex   = "sender~transfer"
 	receiverTransferIndex = "receiver~transfer"
 	transferDrugIndex     = "transfer~drug"
+	drugTransferIndex     = "drug~transfer"
+	gtinSerialIndex       = "gtin~serial~drug"
+	drugScanIndex         = "drug~scan"
+	ownerContainerIndex   = "owner~container"
+	containerDrugIndex    = "container~drug"
+	containerChildIndex   = "container~container"
+	transferReadingIndex  = "transfer~reading"
+	gtinProductIndex      = "gtin~product"
+	productBatchIndex     = "product~batch"
+	activeHoldIndex       = "scope~target~hold"
 )
 
 const (
-	batchKey    = "B"
-	transferKey = "T"
-	drugKey     = "D"
+	batchKey            = "B"
+	transferKey         = "T"
+	drugKey             = "D"
+	orgKey              = "Org"
+	scanKey             = "S"
+	conditionReadingKey = "R"
+	productKey          = "P"
+	holdKey             = "H"
+	dispenseKey         = "Rx"
+	decommissionKey     = "X"
 )
 
+const txIDLength = 16
+
 type SmartContract struct {
 	contractapi.Contract
 }
@@ -38,37 +58,46 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 			ID:       "Org1",
 			Location: "Switzerland",
 			Name:     "PharmaCorp",
-			Type:     "Manufacturer",
+			Type:     model.OrgTypeManufacturer,
 		},
 		{
 			ID:       "Org2",
 			Location: "Indonesia",
 			Name:     "SehatDistribusi",
-			Type:     "Distributor",
+			Type:     model.OrgTypeDistributor,
 		},
 		{
 			ID:       "Org3",
 			Location: "Indonesia",
 			Name:     "ApotekSehat",
-			Type:     "Pharmacy",
+			Type:     model.OrgTypePharmacy,
 		},
 		{
 			ID:       "Org4",
 			Location: "Indonesia",
 			Name:     "Pasien",
-			Type:     "Patient",
+			Type:     model.OrgTypePatient,
+		},
+		{
+			ID:       "Org5",
+			Location: "Indonesia",
+			Name:     "BPOM",
+			Type:     model.OrgTypeRegulator,
 		},
 	}
 
 	for _, org := range organizations {
-		orgJSON, err := json.Marshal(org)
+		exists, err := s.organizationExists(ctx, org.ID)
 		if err != nil {
 			return err
 		}
+		if exists {
+			continue
+		}
 
-		err = ctx.GetStub().PutState(org.ID, orgJSON)
-		if err != nil {
-			return fmt.Errorf("failed to put to world state: %v", err)
+		org.Status = model.OrgStatusActive
+		if err := s.putOrganization(ctx, &org); err != nil {
+			return err
 		}
 	}
 
@@ -124,15 +153,30 @@ func (s *SmartContract) updateDrugTransfer(ctx contractapi.TransactionContextInt
 		return nil, fmt.Errorf("failed to put transfer-drug index to world state: %w", err)
 	}
 
+	drugTransferIndexKey, err := ctx.GetStub().CreateCompositeKey(drugTransferIndex, []string{drug.ID, transferID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %w", err)
+	}
+	if err := ctx.GetStub().PutState(drugTransferIndexKey, value); err != nil {
+		return nil, fmt.Errorf("failed to put drug-transfer index to world state: %w", err)
+	}
+
 	return &drug.ID, nil
 }
 
 func (s *SmartContract) CreateDrug(ctx contractapi.TransactionContextInterface, org *model.Organization, batchID string, drugID string) (string, error) {
+	return s.createDrug(ctx, org, batchID, drugID, "", "")
+}
+
+func (s *SmartContract) createDrug(ctx contractapi.TransactionContextInterface, org *model.Organization, batchID string, drugID string, gtin string, serialNumber string) (string, error) {
 	drug := model.Drug{
-		BatchID:  batchID,
-		ID:       drugID,
-		Location: org.Location,
-		OwnerID:  org.ID,
+		BatchID:      batchID,
+		DocType:      model.DocTypeDrug,
+		GTIN:         gtin,
+		ID:           drugID,
+		Location:     org.Location,
+		OwnerID:      org.ID,
+		SerialNumber: serialNumber,
 	}
 
 	drugJSON, err := json.Marshal(drug)
@@ -163,10 +207,26 @@ func (s *SmartContract) CreateDrug(ctx contractapi.TransactionContextInterface,
 		return "", fmt.Errorf("failed to put owner-drug index to world state: %w", err)
 	}
 
+	if gtin != "" {
+		gtinSerialIndexKey, err := ctx.GetStub().CreateCompositeKey(gtinSerialIndex, []string{gtin, serialNumber, drugID})
+		if err != nil {
+			return "", fmt.Errorf("failed to create composite key: %w", err)
+		}
+		if err := ctx.GetStub().PutState(gtinSerialIndexKey, value); err != nil {
+			return "", fmt.Errorf("failed to put gtin-serial index to world state: %w", err)
+		}
+	}
+
 	return drugID, nil
 }
 
+// GetDrug reads a drug by ID. Drugs written before docType was stored have no
+// docType, so the key prefix is checked as well to keep other records out.
 func (s *SmartContract) GetDrug(ctx contractapi.TransactionContextInterface, drugID string) (*model.Drug, error) {
+	if !strings.HasPrefix(drugID, drugKey) {
+		return nil, fmt.Errorf("drug %s does not exist", drugID)
+	}
+
 	drugJSON, err := ctx.GetStub().GetState(drugID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
@@ -180,6 +240,9 @@ func (s *SmartContract) GetDrug(ctx contractapi.TransactionContextInterface, dru
 	if err != nil {
 		return nil, err
 	}
+	if drug.DocType != "" && drug.DocType != model.DocTypeDrug {
+		return nil, fmt.Errorf("drug %s does not exist", drugID)
+	}
 
 	return &drug, nil
 }
@@ -233,8 +296,21 @@ func (s *SmartContract) GetMyDrug(ctx contractapi.TransactionContextInterface) (
 }
 
 func (s *SmartContract) GetMyAvailDrugs(ctx contractapi.TransactionContextInterface) ([]*model.Drug, error) {
+	heldDrugs, err := s.getHeldTargets(ctx, model.HoldScopeDrug)
+	if err != nil {
+		return nil, err
+	}
+	heldBatches, err := s.getHeldTargets(ctx, model.HoldScopeBatch)
+	if err != nil {
+		return nil, err
+	}
+	heldOwners, err := s.getHeldTargets(ctx, model.HoldScopeOwner)
+	if err != nil {
+		return nil, err
+	}
+
 	return s.getFilteredDrugs(ctx, func(drug *model.Drug, org *model.Organization) bool {
-		return !drug.IsTransferred
+		return !drug.IsTransferred && !drug.IsDispensed && !drug.IsNonSaleable && !heldDrugs[drug.ID] && !heldBatches[drug.BatchID] && !heldOwners[org.ID]
 	})
 }
 
@@ -304,7 +380,14 @@ func (s *SmartContract) GetDrugByTransfer(ctx contractapi.TransactionContextInte
 	return drugs, nil
 }
 
+// GetOrganization only resolves organization records, so callers that write the
+// organization back cannot overwrite another record stored under the given key.
+// Seeded organizations predate the docType field and are accepted without it.
 func (s *SmartContract) GetOrganization(ctx contractapi.TransactionContextInterface, id string) (*model.Organization, error) {
+	if !strings.HasPrefix(id, orgKey) {
+		return nil, fmt.Errorf("organization %s does not exist", id)
+	}
+
 	orgJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
@@ -318,6 +401,9 @@ func (s *SmartContract) GetOrganization(ctx contractapi.TransactionContextInterf
 	if err != nil {
 		return nil, err
 	}
+	if org.DocType != "" && org.DocType != model.DocTypeOrganization {
+		return nil, fmt.Errorf("organization %s does not exist", id)
+	}
 
 	return &org, nil
 }
@@ -329,25 +415,66 @@ func (s *SmartContract) CreateTransfer(ctx contractapi.TransactionContextInterfa
 	}
 
 	var createTransfer dto.CreateTransfer
-	err = json.Unmarshal([]byte(req), &createTransfer)
-	if err != nil {
+	if err := dto.Decode(req, &createTransfer); err != nil {
 		return nil, err
 	}
 
-	transferID, _, err := s.generateModelId(ctx, transferKey)
+	var receiverID string
+	if createTransfer.ReceiverID != nil {
+		receiverID = *createTransfer.ReceiverID
+	}
+	if _, err := s.checkTransferRoute(ctx, org, receiverID, false); err != nil {
+		return nil, err
+	}
+
+	containersID, packedDrugsID, err := s.prepareTransferContainers(ctx, org, createTransfer.ContainersID)
 	if err != nil {
 		return nil, err
 	}
 
-	isAccepted := false
+	transferDrugsID := make([]string, 0, len(createTransfer.DrugsID)+len(packedDrugsID))
+	for _, drugID := range createTransfer.DrugsID {
+		transferDrugsID = append(transferDrugsID, *drugID)
+	}
+	transferDrugsID = append(transferDrugsID, packedDrugsID...)
+
+	packed := make(map[string]bool)
+	for _, drugID := range packedDrugsID {
+		packed[drugID] = true
+	}
+
 	transfer := model.Transfer{
-		ID:         transferID,
-		IsAccepted: isAccepted,
-		// ReceiveDate:  nil,
-		ReceiverID:   *createTransfer.ReceiverID,
-		SenderID:     org.ID,
-		TransferDate: *createTransfer.TransferDate,
+		ContainersID:         containersID,
+		DeclaredTransferDate: createTransfer.TransferDate,
+		ReceiverID:           receiverID,
+	}
+	return s.createTransfer(ctx, org, &transfer, transferDrugsID, packed)
+}
+
+// createTransfer stores a pending transfer of the given drugs from org and marks
+// them as in transit. Drugs packed in a container may only move with it, so they
+// must be listed in packed. Recalled and expired drugs may only move upstream in
+// a return.
+func (s *SmartContract) createTransfer(ctx contractapi.TransactionContextInterface, org *model.Organization, transfer *model.Transfer, transferDrugsID []string, packed map[string]bool) (*model.Transfer, error) {
+	now, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDeclaredTime(ctx, "transfer date", transfer.DeclaredTransferDate, now); err != nil {
+		return nil, err
 	}
+
+	transferID, err := s.generateModelId(ctx, transferKey)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.DocType = model.DocTypeTransfer
+	transfer.ID = transferID
+	transfer.SenderID = org.ID
+	transfer.Status = model.TransferStatusPending
+	transfer.TransferDate = now
+
 	transferJSON, err := json.Marshal(transfer)
 	if err != nil {
 		return nil, err
@@ -362,7 +489,7 @@ func (s *SmartContract) CreateTransfer(ctx contractapi.TransactionContextInterfa
 	if err != nil {
 		return nil, err
 	}
-	receiverTransferIndexKey, err := ctx.GetStub().CreateCompositeKey(receiverTransferIndex, []string{*createTransfer.ReceiverID, transferID})
+	receiverTransferIndexKey, err := ctx.GetStub().CreateCompositeKey(receiverTransferIndex, []string{transfer.ReceiverID, transferID})
 	if err != nil {
 		return nil, err
 	}
@@ -373,18 +500,58 @@ func (s *SmartContract) CreateTransfer(ctx contractapi.TransactionContextInterfa
 		return nil, err
 	}
 
-	for _, drugID := range createTransfer.DrugsID {
-		drug, err := s.GetDrug(ctx, *drugID)
+	var drugsIDs []string
+	batches := make(map[string]*model.Batch)
+	for _, drugID := range transferDrugsID {
+		drug, err := s.GetDrug(ctx, drugID)
 		if err != nil {
 			return nil, err
 		}
 
 		if drug.IsTransferred {
-			return nil, fmt.Errorf("drug %s has already been transferred", *drugID)
+			return nil, fmt.Errorf("drug %s has already been transferred", drugID)
 		}
 
 		if drug.OwnerID != org.ID {
-			return nil, fmt.Errorf("drug %s does not belong to the sender", *drugID)
+			return nil, fmt.Errorf("drug %s does not belong to the sender", drugID)
+		}
+
+		if drug.IsDispensed {
+			return nil, fmt.Errorf("drug %s has been dispensed and cannot be transferred", drugID)
+		}
+
+		if drug.IsDecommissioned {
+			return nil, fmt.Errorf("drug %s has been decommissioned and cannot be transferred", drugID)
+		}
+
+		if drug.DecommissionID != "" {
+			return nil, fmt.Errorf("drug %s is awaiting confirmation of decommission %s and cannot be transferred", drugID, drug.DecommissionID)
+		}
+
+		if drug.IsRecalled && transfer.Return == nil {
+			return nil, fmt.Errorf("drug %s belongs to recalled batch %s", drugID, drug.BatchID)
+		}
+
+		if drug.IsNonSaleable && transfer.Return == nil {
+			return nil, fmt.Errorf("drug %s was returned as non-saleable and can only be returned or decommissioned", drugID)
+		}
+
+		if transfer.Return == nil {
+			if err := s.checkNotExpired(ctx, batches, drug, now); err != nil {
+				return nil, err
+			}
+		}
+
+		holdID, err := s.findActiveHold(ctx, drug)
+		if err != nil {
+			return nil, err
+		}
+		if holdID != "" {
+			return nil, fmt.Errorf("drug %s is under quarantine hold %s", drugID, holdID)
+		}
+
+		if drug.ContainerID != "" && !packed[drugID] {
+			return nil, fmt.Errorf("drug %s is packed in container %s, transfer the container or disaggregate it first", drugID, drug.ContainerID)
 		}
 
 		drug.IsTransferred = true
@@ -393,21 +560,42 @@ func (s *SmartContract) CreateTransfer(ctx contractapi.TransactionContextInterfa
 		if err != nil {
 			return nil, err
 		}
-		if err := ctx.GetStub().PutState(*drugID, drugJSON); err != nil {
+		if err := ctx.GetStub().PutState(drugID, drugJSON); err != nil {
 			return nil, err
 		}
 
-		transferDrugIndexKey, err := ctx.GetStub().CreateCompositeKey(transferDrugIndex, []string{transferID, *drugID})
+		transferDrugIndexKey, err := ctx.GetStub().CreateCompositeKey(transferDrugIndex, []string{transferID, drugID})
 		if err != nil {
 			return nil, err
 		}
 		if err := ctx.GetStub().PutState(transferDrugIndexKey, value); err != nil {
 			return nil, err
 		}
+
+		drugTransferIndexKey, err := ctx.GetStub().CreateCompositeKey(drugTransferIndex, []string{drugID, transferID})
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.GetStub().PutState(drugTransferIndexKey, value); err != nil {
+			return nil, err
+		}
+
+		drugsIDs = append(drugsIDs, drugID)
 	}
-	log.Printf("Drugs transferred: %v\n", createTransfer.DrugsID)
+	log.Printf("Drugs transferred: %v\n", drugsIDs)
 
-	return &transfer, nil
+	if err := s.emitEvent(ctx, event.TypeTransferCreated, event.TransferCreated{
+		ContainersID: transfer.ContainersID,
+		DrugsID:      drugsIDs,
+		IsReturn:     transfer.Return != nil,
+		ReceiverID:   transfer.ReceiverID,
+		SenderID:     transfer.SenderID,
+		TransferID:   transfer.ID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
 }
 
 func (s *SmartContract) GetTransfer(ctx contractapi.TransactionContextInterface, id string) (*model.Transfer, error) {
@@ -424,9 +612,102 @@ func (s *SmartContract) GetTransfer(ctx contractapi.TransactionContextInterface,
 		return nil, fmt.Errorf("failed to unmarshal transfer: %w", err)
 	}
 
+	if transfer.Status == "" {
+		status, err := legacyTransferStatus(transferJSON, &transfer)
+		if err != nil {
+			return nil, err
+		}
+		transfer.Status = status
+	}
+
 	return &transfer, nil
 }
 
+// legacyTransferStatus derives the status of transfers written before Status existed,
+// when only isAccepted was stored and a zero ReceiveDate meant the transfer was pending.
+func legacyTransferStatus(transferJSON []byte, transfer *model.Transfer) (string, error) {
+	var legacy struct {
+		IsAccepted bool `json:"isAccepted"`
+	}
+	if err := json.Unmarshal(transferJSON, &legacy); err != nil {
+		return "", fmt.Errorf("failed to unmarshal transfer: %w", err)
+	}
+
+	switch {
+	case legacy.IsAccepted:
+		return model.TransferStatusAccepted, nil
+	case transfer.ReceiveDate.IsZero():
+		return model.TransferStatusPending, nil
+	default:
+		return model.TransferStatusRejected, nil
+	}
+}
+
+func (s *SmartContract) CancelTransfer(ctx contractapi.TransactionContextInterface, transferID string) (*model.Transfer, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	transfer, err := s.GetTransfer(ctx, transferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+
+	if org.ID != transfer.SenderID {
+		return nil, fmt.Errorf("only the sender can cancel the transfer")
+	}
+
+	if transfer.Status != model.TransferStatusPending {
+		return nil, fmt.Errorf("transfer %s has already been processed with status %s", transfer.ID, transfer.Status)
+	}
+
+	transfer.Status = model.TransferStatusCancelled
+
+	drugs, err := s.GetDrugByTransfer(ctx, transfer.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transferred drugs: %w", err)
+	}
+
+	var drugsIDs []string
+	for _, drug := range drugs {
+		drug.IsTransferred = false
+
+		drugJSON, err := json.Marshal(drug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal drug: %w", err)
+		}
+
+		if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+			return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+		}
+
+		drugsIDs = append(drugsIDs, drug.ID)
+	}
+	log.Printf("Drugs released: %v\n", drugsIDs)
+
+	for _, containerID := range transfer.ContainersID {
+		if err := s.releaseContainer(ctx, containerID, transfer.SenderID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.emitTransferProcessed(ctx, event.TypeTransferCancelled, transfer, nil, drugsIDs); err != nil {
+		return nil, err
+	}
+
+	transferJSON, err := json.Marshal(transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transfer: %w", err)
+	}
+
+	if err := ctx.GetStub().PutState(transfer.ID, transferJSON); err != nil {
+		return nil, fmt.Errorf("failed to put transfer to world state: %w", err)
+	}
+
+	return transfer, nil
+}
+
 func (s *SmartContract) GetMyOutTransfer(ctx contractapi.TransactionContextInterface) ([]*model.Transfer, error) {
 	return s.getMyTransfer(ctx, false)
 }
@@ -501,31 +782,50 @@ func (s *SmartContract) validateProcessTransfer(ctx contractapi.TransactionConte
 	}
 
 	var processTransfer dto.ProcessTransfer
-	if err := json.Unmarshal([]byte(req), &processTransfer); err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to unmarshal request: %w", err)
+	if err := dto.Decode(req, &processTransfer); err != nil {
+		return nil, nil, nil, err
+	}
+
+	transfer, err := s.getPendingTransferForReceiver(ctx, org, processTransfer.TransferID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to validate process transfer: %w", err)
 	}
 
-	transfer, err := s.GetTransfer(ctx, processTransfer.TransferID)
+	return transfer, org, &processTransfer, nil
+}
+
+func (s *SmartContract) getPendingTransferForReceiver(ctx contractapi.TransactionContextInterface, org *model.Organization, transferID string) (*model.Transfer, error) {
+	transfer, err := s.GetTransfer(ctx, transferID)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get transfer: %w", err)
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
 	}
 
 	if org.ID != transfer.ReceiverID {
-		return nil, nil, nil, fmt.Errorf("only the receiver can accept the transfer")
+		return nil, fmt.Errorf("only the receiver can accept the transfer")
 	}
 
-	return transfer, org, &processTransfer, nil
+	if transfer.Status != model.TransferStatusPending {
+		return nil, fmt.Errorf("transfer %s has already been processed with status %s", transfer.ID, transfer.Status)
+	}
+
+	return transfer, nil
 }
 
 func (s *SmartContract) AcceptTransfer(ctx contractapi.TransactionContextInterface, req string) (*model.Transfer, error) {
 	transfer, org, processTransfer, err := s.validateProcessTransfer(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to validate process transfer: %w", err)
+		return nil, err
+	}
+
+	transfer.Status = model.TransferStatusAccepted
+	if err := s.stampReceiveDate(ctx, transfer, processTransfer.ReceiveDate); err != nil {
+		return nil, err
 	}
 
-	isAccepted := true
-	transfer.IsAccepted = isAccepted
-	transfer.ReceiveDate = *processTransfer.ReceiveDate
+	if transfer.HasExcursion && !processTransfer.OverrideExcursion {
+		return nil, fmt.Errorf("transfer %s had a cold-chain excursion affecting batches %v, refuse the affected drugs or accept with an explicit override", transfer.ID, transfer.ExcursionBatchesID)
+	}
+	transfer.IsExcursionOverridden = transfer.HasExcursion
 
 	transferDrugsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(transferDrugIndex, []string{transfer.ID})
 	if err != nil {
@@ -552,26 +852,8 @@ func (s *SmartContract) AcceptTransfer(ctx contractapi.TransactionContextInterfa
 				return nil, fmt.Errorf("failed to get drug: %w", err)
 			}
 
-			drug.IsTransferred = false
-			drug.Location = org.Location
-
-			_, err = s.updateDrugOwner(ctx, drug, org.ID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to set drug owner: %w", err)
-			}
-
-			_, err = s.updateDrugTransfer(ctx, drug, transfer.ID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to set drug transfer ID: %w", err)
-			}
-
-			drugJSOn, err := json.Marshal(drug)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal drug: %w", err)
-			}
-
-			if err := ctx.GetStub().PutState(drug.ID, drugJSOn); err != nil {
-				return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+			if err := s.receiveTransferredDrug(ctx, drug, org, transfer); err != nil {
+				return nil, err
 			}
 
 			drugsIDs = append(drugsIDs, drug.ID)
@@ -579,6 +861,16 @@ func (s *SmartContract) AcceptTransfer(ctx contractapi.TransactionContextInterfa
 	}
 	log.Printf("Drugs accepted: %v\n", drugsIDs)
 
+	for _, containerID := range transfer.ContainersID {
+		if err := s.releaseContainer(ctx, containerID, org.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.emitTransferProcessed(ctx, event.TypeTransferAccepted, transfer, drugsIDs, nil); err != nil {
+		return nil, err
+	}
+
 	transferJSON, err := json.Marshal(transfer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal transfer: %w", err)
@@ -594,12 +886,13 @@ func (s *SmartContract) AcceptTransfer(ctx contractapi.TransactionContextInterfa
 func (s *SmartContract) RejectTransfer(ctx contractapi.TransactionContextInterface, req string) (*model.Transfer, error) {
 	transfer, _, processTransfer, err := s.validateProcessTransfer(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to validate process transfer: %w", err)
+		return nil, err
 	}
 
-	isAccepted := false
-	transfer.IsAccepted = isAccepted
-	transfer.ReceiveDate = *processTransfer.ReceiveDate
+	transfer.Status = model.TransferStatusRejected
+	if err := s.stampReceiveDate(ctx, transfer, processTransfer.ReceiveDate); err != nil {
+		return nil, err
+	}
 
 	transferDrugsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(transferDrugIndex, []string{transfer.ID})
 	if err != nil {
@@ -626,20 +919,8 @@ func (s *SmartContract) RejectTransfer(ctx contractapi.TransactionContextInterfa
 				return nil, fmt.Errorf("failed to get drug: %w", err)
 			}
 
-			drug.IsTransferred = false
-
-			_, err = s.updateDrugTransfer(ctx, drug, "")
-			if err != nil {
-				return nil, fmt.Errorf("failed to remove drug transfer ID: %w", err)
-			}
-
-			drugJSON, err := json.Marshal(drug)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal drug: %w", err)
-			}
-
-			if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
-				return nil, fmt.Errorf("failed to put drug to world state: %w", err)
+			if err := s.returnTransferredDrug(ctx, drug); err != nil {
+				return nil, err
 			}
 
 			drugsIDs = append(drugsIDs, drug.ID)
@@ -647,6 +928,16 @@ func (s *SmartContract) RejectTransfer(ctx contractapi.TransactionContextInterfa
 	}
 	log.Printf("Drugs rejected: %v\n", drugsIDs)
 
+	for _, containerID := range transfer.ContainersID {
+		if err := s.releaseContainer(ctx, containerID, transfer.SenderID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.emitTransferProcessed(ctx, event.TypeTransferRejected, transfer, nil, drugsIDs); err != nil {
+		return nil, err
+	}
+
 	transferJSON, err := json.Marshal(transfer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal transfer: %w", err)
@@ -659,38 +950,231 @@ func (s *SmartContract) RejectTransfer(ctx contractapi.TransactionContextInterfa
 	return transfer, nil
 }
 
+func (s *SmartContract) PartiallyAcceptTransfer(ctx contractapi.TransactionContextInterface, req string) (*model.Transfer, error) {
+	org, err := s.getOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization ID: %w", err)
+	}
+
+	var partialAccept dto.PartialAcceptTransfer
+	if err := dto.Decode(req, &partialAccept); err != nil {
+		return nil, err
+	}
+
+	transfer, err := s.getPendingTransferForReceiver(ctx, org, partialAccept.TransferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate process transfer: %w", err)
+	}
+
+	outcomes := make(map[string]*model.TransferDrugOutcome)
+	for _, drugID := range partialAccept.ReceivedDrugsID {
+		outcomes[drugID] = &model.TransferDrugOutcome{
+			DrugID:  drugID,
+			Outcome: model.DrugOutcomeReceived,
+		}
+	}
+	for _, refused := range partialAccept.RefusedDrugs {
+		outcomes[refused.DrugID] = &model.TransferDrugOutcome{
+			DrugID:     refused.DrugID,
+			Outcome:    model.DrugOutcomeRefused,
+			ReasonCode: refused.ReasonCode,
+		}
+	}
+
+	drugs, err := s.GetDrugByTransfer(ctx, transfer.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transferred drugs: %w", err)
+	}
+	if len(drugs) != len(outcomes) {
+		return nil, fmt.Errorf("transfer %s contains %d drugs but %d were listed", transfer.ID, len(drugs), len(outcomes))
+	}
+
+	var receivedIDs, refusedIDs []string
+	transfer.DrugOutcomes = make([]*model.TransferDrugOutcome, 0, len(drugs))
+	for _, drug := range drugs {
+		outcome, ok := outcomes[drug.ID]
+		if !ok {
+			return nil, fmt.Errorf("drug %s of transfer %s was not listed as received or refused", drug.ID, transfer.ID)
+		}
+
+		if outcome.Outcome == model.DrugOutcomeReceived {
+			if err := checkExcursionOverride(transfer, drug, partialAccept.OverrideExcursion); err != nil {
+				return nil, err
+			}
+			if err := s.receiveTransferredDrug(ctx, drug, org, transfer); err != nil {
+				return nil, err
+			}
+			receivedIDs = append(receivedIDs, drug.ID)
+		} else {
+			if err := s.returnTransferredDrug(ctx, drug); err != nil {
+				return nil, err
+			}
+			refusedIDs = append(refusedIDs, drug.ID)
+		}
+
+		transfer.DrugOutcomes = append(transfer.DrugOutcomes, outcome)
+	}
+	log.Printf("Drugs accepted: %v, drugs refused: %v\n", receivedIDs, refusedIDs)
+
+	if err := s.settlePartialContainers(ctx, transfer, outcomes); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(refusedIDs) == 0:
+		transfer.Status = model.TransferStatusAccepted
+	case len(receivedIDs) == 0:
+		transfer.Status = model.TransferStatusRejected
+	default:
+		transfer.Status = model.TransferStatusPartiallyAccepted
+	}
+	if err := s.stampReceiveDate(ctx, transfer, partialAccept.ReceiveDate); err != nil {
+		return nil, err
+	}
+
+	eventType := event.TypeTransferPartiallyAccepted
+	switch transfer.Status {
+	case model.TransferStatusAccepted:
+		eventType = event.TypeTransferAccepted
+	case model.TransferStatusRejected:
+		eventType = event.TypeTransferRejected
+	}
+	if err := s.emitTransferProcessed(ctx, eventType, transfer, receivedIDs, refusedIDs); err != nil {
+		return nil, err
+	}
+
+	transferJSON, err := json.Marshal(transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transfer: %w", err)
+	}
+
+	if err := ctx.GetStub().PutState(transfer.ID, transferJSON); err != nil {
+		return nil, fmt.Errorf("failed to put transfer to world state: %w", err)
+	}
+
+	return transfer, nil
+}
+
+func (s *SmartContract) receiveTransferredDrug(ctx contractapi.TransactionContextInterface, drug *model.Drug, org *model.Organization, transfer *model.Transfer) error {
+	if drug.IsRecalled && transfer.Return == nil {
+		return fmt.Errorf("drug %s belongs to recalled batch %s", drug.ID, drug.BatchID)
+	}
+	if transfer.Return != nil && transfer.Return.Disposition == model.ReturnDispositionNonSaleable {
+		drug.IsNonSaleable = true
+	}
+
+	drug.IsTransferred = false
+	drug.Location = org.Location
+
+	_, err := s.updateDrugOwner(ctx, drug, org.ID)
+	if err != nil {
+		return fmt.Errorf("failed to set drug owner: %w", err)
+	}
+
+	_, err = s.updateDrugTransfer(ctx, drug, transfer.ID)
+	if err != nil {
+		return fmt.Errorf("failed to set drug transfer ID: %w", err)
+	}
+
+	drugJSON, err := json.Marshal(drug)
+	if err != nil {
+		return fmt.Errorf("failed to marshal drug: %w", err)
+	}
+
+	if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+		return fmt.Errorf("failed to put drug to world state: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SmartContract) returnTransferredDrug(ctx contractapi.TransactionContextInterface, drug *model.Drug) error {
+	drug.IsTransferred = false
+
+	_, err := s.updateDrugTransfer(ctx, drug, "")
+	if err != nil {
+		return fmt.Errorf("failed to remove drug transfer ID: %w", err)
+	}
+
+	drugJSON, err := json.Marshal(drug)
+	if err != nil {
+		return fmt.Errorf("failed to marshal drug: %w", err)
+	}
+
+	if err := ctx.GetStub().PutState(drug.ID, drugJSON); err != nil {
+		return fmt.Errorf("failed to put drug to world state: %w", err)
+	}
+
+	return nil
+}
+
 func (s *SmartContract) CreateBatch(ctx contractapi.TransactionContextInterface, req string) (*model.Batch, error) {
 	org, err := s.getOrg(ctx)
 	if err != nil {
 		fmt.Printf("error: failed to get organization ID: %v\n", err)
 		return nil, fmt.Errorf("failed to get organization ID: %v", err)
 	}
-	if org.Type != "Manufacturer" {
+	if org.Type != model.OrgTypeManufacturer {
 		err := fmt.Errorf("only manufacturers can create batches")
 		fmt.Printf("error: %v\n", err)
 		return nil, err
 	}
 
 	var createBatch dto.CreateBatch
-	err = json.Unmarshal([]byte(req), &createBatch)
-	if err != nil {
-		fmt.Printf("error: failed to unmarshal request: %v\n", err)
-		return nil, fmt.Errorf("failed to unmarshal request: %v", err)
+	if err := dto.Decode(req, &createBatch); err != nil {
+		fmt.Printf("error: invalid request: %v\n", err)
+		return nil, err
 	}
 
-	batchID, _, err := s.generateModelId(ctx, batchKey)
+	batchID, err := s.generateModelId(ctx, batchKey)
 	if err != nil {
 		fmt.Printf("error: failed to generate batch ID: %v\n", err)
 		return nil, fmt.Errorf("failed to generate batch ID: %v", err)
 	}
 
+	var product *model.Product
+	if createBatch.ProductID != "" {
+		product, err = s.GetProduct(ctx, createBatch.ProductID)
+		if err != nil {
+			fmt.Printf("error: failed to get product: %v\n", err)
+			return nil, fmt.Errorf("failed to get product: %v", err)
+		}
+		if product.OwnerID != org.ID {
+			err := fmt.Errorf("only the brand owner of product %s can create batches of it", product.ID)
+			fmt.Printf("error: %v\n", err)
+			return nil, err
+		}
+		if err := createBatch.ValidateProduct(product); err != nil {
+			fmt.Printf("error: invalid request: %v\n", err)
+			return nil, err
+		}
+
+		createBatch.DrugName = product.GenericName
+		createBatch.GTIN = product.GTIN
+	}
+
+	var serialNumbers []string
+	if createBatch.GTIN != "" {
+		serialNumbers, err = s.prepareSerialNumbers(ctx, &createBatch)
+		if err != nil {
+			fmt.Printf("error: failed to prepare serial numbers: %v\n", err)
+			return nil, fmt.Errorf("failed to prepare serial numbers: %v", err)
+		}
+	}
+
 	batch := model.Batch{
+		DocType:             model.DocTypeBatch,
 		DrugName:            createBatch.DrugName,
-		ExpiryDate:          createBatch.ExpiryDate,
+		ExpiryDate:          createBatch.ExpiryDate.UTC(),
+		GTIN:                createBatch.GTIN,
 		ID:                  batchID,
+		LotNumber:           createBatch.LotNumber,
+		ManufacturerID:      org.ID,
 		ManufacturerName:    org.Name,
 		ManufactureLocation: org.Location,
-		ProductionDate:      createBatch.ProductionDate,
+		ProductID:           createBatch.ProductID,
+		ProductionDate:      createBatch.ProductionDate.UTC(),
+		StorageConditions:   createBatch.StorageConditions,
 	}
 	batchJSON, err := json.Marshal(batch)
 	if err != nil {
@@ -704,36 +1188,64 @@ func (s *SmartContract) CreateBatch(ctx contractapi.TransactionContextInterface,
 		return nil, fmt.Errorf("failed to put batch to world state: %v", err)
 	}
 
-	_, drugInt, err := s.generateModelId(ctx, drugKey)
+	if product != nil {
+		productBatchIndexKey, err := ctx.GetStub().CreateCompositeKey(productBatchIndex, []string{product.ID, batch.ID})
+		if err != nil {
+			fmt.Printf("error: failed to create composite key: %v\n", err)
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+		if err := ctx.GetStub().PutState(productBatchIndexKey, []byte{0x00}); err != nil {
+			fmt.Printf("error: failed to put product-batch index to world state: %v\n", err)
+			return nil, fmt.Errorf("failed to put product-batch index to world state: %v", err)
+		}
+	}
+
+	drugIDPrefix, err := s.generateModelId(ctx, drugKey)
 	if err != nil {
 		fmt.Printf("error: failed to generate drug ID: %v\n", err)
 		return nil, fmt.Errorf("failed to generate drug ID: %v", err)
 	}
 
-	var drugsIDs []string
 	for i := range createBatch.Amount {
-		currDrugInt := drugInt + i
-		drugID := s.formatModelId(drugKey, currDrugInt)
+		drugID := s.formatModelId(drugIDPrefix, i+1)
 
-		drugID, err = s.CreateDrug(ctx, org, batch.ID, drugID)
-		if err != nil {
+		var serialNumber string
+		if serialNumbers != nil {
+			serialNumber = serialNumbers[i]
+		}
+
+		if _, err := s.createDrug(ctx, org, batch.ID, drugID, batch.GTIN, serialNumber); err != nil {
 			fmt.Printf("error: failed to create drug: %v\n", err)
 			return nil, fmt.Errorf("failed to create drug: %v", err)
 		}
-		drugsIDs = append(drugsIDs, drugID)
 	}
-	fmt.Printf("Drugs created: %v\n", drugsIDs)
-
-	err = s.saveModelId(ctx, drugKey, (drugInt-1)+createBatch.Amount)
+	fmt.Printf("Drugs created: %d with prefix %s\n", createBatch.Amount, drugIDPrefix)
+
+	err = s.emitEvent(ctx, event.TypeBatchCreated, event.BatchCreated{
+		Amount:         createBatch.Amount,
+		BatchID:        batch.ID,
+		DrugIDPrefix:   drugIDPrefix,
+		DrugName:       batch.DrugName,
+		FirstDrugID:    s.formatModelId(drugIDPrefix, 1),
+		LastDrugID:     s.formatModelId(drugIDPrefix, createBatch.Amount),
+		ManufacturerID: batch.ManufacturerID,
+		ProductID:      batch.ProductID,
+	})
 	if err != nil {
-		fmt.Printf("error: failed to save drug ID: %v\n", err)
-		return nil, fmt.Errorf("failed to save drug ID: %v", err)
+		fmt.Printf("error: failed to emit event: %v\n", err)
+		return nil, fmt.Errorf("failed to emit event: %v", err)
 	}
 
 	return &batch, nil
 }
 
+// GetBatch reads a batch by ID. Like GetDrug it accepts legacy batches without a
+// docType only under the batch key prefix.
 func (s *SmartContract) GetBatch(ctx contractapi.TransactionContextInterface, id string) (*model.Batch, error) {
+	if !strings.HasPrefix(id, batchKey) {
+		return nil, fmt.Errorf("batch %s does not exist", id)
+	}
+
 	batchJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read from world state: %v", err)
@@ -747,6 +1259,9 @@ func (s *SmartContract) GetBatch(ctx contractapi.TransactionContextInterface, id
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal batch: %v", err)
 	}
+	if batch.DocType != "" && batch.DocType != model.DocTypeBatch {
+		return nil, fmt.Errorf("batch %s does not exist", id)
+	}
 
 	return &batch, nil
 }
@@ -756,24 +1271,29 @@ func (s *SmartContract) UpdateBatch(ctx contractapi.TransactionContextInterface,
 	if err != nil {
 		return nil, fmt.Errorf("failed to get organization ID: %v", err)
 	}
-	if org.Type != "Manufacturer" {
+	if org.Type != model.OrgTypeManufacturer {
 		return nil, fmt.Errorf("only manufacturers can update batches")
 	}
 
 	var updateBatch dto.UpdateBatch
-	err = json.Unmarshal([]byte(req), &updateBatch)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal request: %v", err)
+	if err := dto.Decode(req, &updateBatch); err != nil {
+		return nil, err
 	}
 
 	batch, err := s.GetBatch(ctx, batchID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get batch: %v", err)
 	}
+	if !isBatchManufacturer(batch, org) {
+		return nil, fmt.Errorf("only the manufacturer of batch %s can update it", batchID)
+	}
+	if batch.ProductID != "" && updateBatch.DrugName != batch.DrugName {
+		return nil, fmt.Errorf("the drug name of batch %s comes from product %s, update the product instead", batchID, batch.ProductID)
+	}
 
 	batch.DrugName = updateBatch.DrugName
-	batch.ExpiryDate = updateBatch.ExpiryDate
-	batch.ProductionDate = updateBatch.ProductionDate
+	batch.ExpiryDate = updateBatch.ExpiryDate.UTC()
+	batch.ProductionDate = updateBatch.ProductionDate.UTC()
 
 	batchJSON, err := json.Marshal(batch)
 	if err != nil {
@@ -785,6 +1305,14 @@ func (s *SmartContract) UpdateBatch(ctx contractapi.TransactionContextInterface,
 		return nil, fmt.Errorf("failed to put batch to world state: %v", err)
 	}
 
+	err = s.emitEvent(ctx, event.TypeBatchUpdated, event.BatchUpdated{
+		BatchID:        batch.ID,
+		ManufacturerID: org.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to emit event: %v", err)
+	}
+
 	return batch, nil
 }
 
@@ -814,22 +1342,32 @@ func (s *SmartContract) GetAllBatches(ctx contractapi.TransactionContextInterfac
 }
 
 func (s *SmartContract) getOrg(ctx contractapi.TransactionContextInterface) (*model.Organization, error) {
-	mspID, err := cid.GetMSPID(ctx.GetStub())
+	orgID, err := s.getOrgID(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get MSP ID: %v", err)
+		return nil, err
 	}
 
-	orgID := strings.TrimSuffix(mspID, "MSP")
-
 	org, err := s.GetOrganization(ctx, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get organization: %v", err)
 	}
+	if org.Status == model.OrgStatusSuspended {
+		return nil, fmt.Errorf("organization %s is suspended", org.ID)
+	}
 	return org, nil
 }
 
+func (s *SmartContract) getOrgID(ctx contractapi.TransactionContextInterface) (string, error) {
+	mspID, err := cid.GetMSPID(ctx.GetStub())
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSP ID: %v", err)
+	}
+
+	return strings.TrimSuffix(mspID, "MSP"), nil
+}
+
 func (s *SmartContract) GetAllOrganizations(ctx contractapi.TransactionContextInterface) ([]*model.Organization, error) {
-	resIterator, err := ctx.GetStub().GetStateByRange("Org", "Org~")
+	resIterator, err := ctx.GetStub().GetStateByRange(orgKey, orgKey+"~")
 	if err != nil {
 		return nil, err
 	}
@@ -862,42 +1400,40 @@ func (s *SmartContract) BatchExists(ctx contractapi.TransactionContextInterface,
 	return batchJSON != nil, nil
 }
 
-func (s *SmartContract) generateModelId(ctx contractapi.TransactionContextInterface, modelKey string) (string, int, error) {
-	latestIDKey := fmt.Sprintf("LatestID_%s", modelKey)
-
-	latestIDBytes, err := ctx.GetStub().GetState(latestIDKey)
+// generateModelId derives an ID from the proposal timestamp and transaction ID, which
+// every endorser sees identically, so no shared counter key is read or written. The
+// zero-padded nanosecond timestamp keeps IDs in creation order and sorts them after
+// the legacy counter-based IDs.
+func (s *SmartContract) generateModelId(ctx contractapi.TransactionContextInterface, modelKey string) (string, error) {
+	txTime, err := s.getTxTime(ctx)
 	if err != nil {
-		return "", -1, fmt.Errorf("failed to get latest ID: %v", err)
-	}
-
-	latestNum := 0
-	if latestIDBytes != nil {
-		latestNum, err = strconv.Atoi(string(latestIDBytes))
-		if err != nil {
-			return "", -1, fmt.Errorf("failed to parse latest ID number: %v", err)
-		}
+		return "", err
 	}
 
-	newIDNum := latestNum + 1
-
-	err = ctx.GetStub().PutState(latestIDKey, []byte(strconv.Itoa(newIDNum)))
-	if err != nil {
-		return "", -1, fmt.Errorf("failed to store new latest ID: %v", err)
+	txID := ctx.GetStub().GetTxID()
+	if len(txID) < txIDLength {
+		return "", fmt.Errorf("transaction ID %q is too short to derive an ID", txID)
 	}
 
-	formattedID := s.formatModelId(modelKey, newIDNum)
-	return formattedID, newIDNum, err
+	formattedID := fmt.Sprintf("%s%019d%s", modelKey, txTime.UnixNano(), txID[:txIDLength])
+	return formattedID, nil
 }
 
-func (s *SmartContract) saveModelId(ctx contractapi.TransactionContextInterface, modelKey string, id int) error {
-	latestIDKey := fmt.Sprintf("LatestID_%s", modelKey)
-
-	return ctx.GetStub().PutState(latestIDKey, []byte(strconv.Itoa(id)))
+// formatModelId appends a sequence number to an ID generated in the same transaction,
+// for models such as drugs that are created many at a time. dto.CreateBatch caps the
+// amount so the sequence never outgrows its 8 digits.
+func (s *SmartContract) formatModelId(prefix string, seq int) string {
+	formattedID := fmt.Sprintf("%s%08d", prefix, seq)
+	return formattedID
 }
 
-func (s *SmartContract) formatModelId(modelKey string, id int) string {
-	formattedID := fmt.Sprintf("%s%016d", modelKey, id)
-	return formattedID
+func (s *SmartContract) getTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %w", err)
+	}
+
+	return txTimestamp.AsTime(), nil
 }
 
 func (s *SmartContract) GetHistoryDrug(ctx contractapi.TransactionContextInterface, drugID string) ([]*model.HistoryDrug, error) {
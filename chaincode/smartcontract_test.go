@@ -0,0 +1,403 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// forgedDrugID holds a product record under a drug-shaped key, so only the
+// docType tells it apart from a drug.
+const forgedDrugID = "D9999999999999999999"
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	vJSON, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	return string(vJSON)
+}
+
+func TestWrongTypeIDRejectedBeforeWrite(t *testing.T) {
+	s := new(SmartContract)
+	updateBatch := func(t *testing.T) string {
+		return mustJSON(t, map[string]interface{}{
+			"DrugName":       "Paracetamol",
+			"ExpiryDate":     testTxTime.AddDate(2, 0, 0),
+			"ProductionDate": testTxTime.AddDate(0, -1, 0),
+		})
+	}
+	updateProduct := func(t *testing.T) string {
+		return mustJSON(t, map[string]interface{}{
+			"DosageForm":                   "Tablet",
+			"GenericName":                  "Paracetamol",
+			"MarketingAuthorizationNumber": "DBL1234567890A1",
+			"PackSize":                     20,
+			"Strength":                     "500 mg",
+		})
+	}
+	recordScan := func(drugID string) func(t *testing.T) string {
+		return func(t *testing.T) string {
+			return mustJSON(t, map[string]interface{}{"DrugID": drugID, "Location": "Jakarta"})
+		}
+	}
+	drugsID := func(drugID string, extra map[string]interface{}) func(t *testing.T) string {
+		return func(t *testing.T) string {
+			req := map[string]interface{}{"DrugsID": []string{drugID}}
+			for k, v := range extra {
+				req[k] = v
+			}
+			return mustJSON(t, req)
+		}
+	}
+	decommission := map[string]interface{}{
+		"Kind":       model.DecommissionKindDestroyed,
+		"ReasonCode": model.DecommissionReasonExpired,
+		"WitnessID":  testRegulatorID,
+	}
+	aggregate := map[string]interface{}{
+		"ContainerID": "106141411234567897",
+		"Level":       model.ContainerLevelCase,
+	}
+
+	tests := []struct {
+		name     string
+		callerID string
+		req      func(t *testing.T) string
+		invoke   func(ctx contractapi.TransactionContextInterface, req string) error
+	}{
+		{"RecordScan with a product ID", testPharmacyID, recordScan(testProductID), func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.RecordScan(ctx, req)
+			return err
+		}},
+		{"RecordScan with an organization ID", testPharmacyID, recordScan(testManufacturerID), func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.RecordScan(ctx, req)
+			return err
+		}},
+		{"RecordScan with a forged drug key", testPharmacyID, recordScan(forgedDrugID), func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.RecordScan(ctx, req)
+			return err
+		}},
+		{"ClearDrugAlerts with a batch ID", testRegulatorID, nil, func(ctx contractapi.TransactionContextInterface, _ string) error {
+			_, err := s.ClearDrugAlerts(ctx, testBatchID)
+			return err
+		}},
+		{"ClearDrugAlerts with a forged drug key", testRegulatorID, nil, func(ctx contractapi.TransactionContextInterface, _ string) error {
+			_, err := s.ClearDrugAlerts(ctx, forgedDrugID)
+			return err
+		}},
+		{"RecallBatch with a drug ID", testRegulatorID, nil, func(ctx contractapi.TransactionContextInterface, _ string) error {
+			_, err := s.RecallBatch(ctx, testDrugID, "Contamination", model.RecallSeverityClassI)
+			return err
+		}},
+		{"RecallBatch with an organization ID", testRegulatorID, nil, func(ctx contractapi.TransactionContextInterface, _ string) error {
+			_, err := s.RecallBatch(ctx, testManufacturerID, "Contamination", model.RecallSeverityClassI)
+			return err
+		}},
+		{"UpdateBatch with a drug ID", testManufacturerID, updateBatch, func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.UpdateBatch(ctx, testDrugID, req)
+			return err
+		}},
+		{"UpdateProduct with a batch ID", testManufacturerID, updateProduct, func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.UpdateProduct(ctx, testBatchID, req)
+			return err
+		}},
+		{"UpdateProduct with a drug ID", testManufacturerID, updateProduct, func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.UpdateProduct(ctx, testDrugID, req)
+			return err
+		}},
+		{"ReleaseHold with a product ID", testRegulatorID, nil, func(ctx contractapi.TransactionContextInterface, _ string) error {
+			_, err := s.ReleaseHold(ctx, testProductID)
+			return err
+		}},
+		{"PlaceHold on a drug with a batch ID", testRegulatorID, func(t *testing.T) string {
+			return mustJSON(t, map[string]interface{}{"Reason": "Tampering", "Scope": model.HoldScopeDrug, "TargetID": testBatchID})
+		}, func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.PlaceHold(ctx, req)
+			return err
+		}},
+		{"UpdateOrganization with a batch ID", testRegulatorID, func(t *testing.T) string {
+			return mustJSON(t, map[string]interface{}{"Location": "Jakarta", "Name": "Kimia Farma", "Type": model.OrgTypeManufacturer})
+		}, func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.UpdateOrganization(ctx, testBatchID, req)
+			return err
+		}},
+		{"SuspendOrganization with a batch ID", testRegulatorID, nil, func(ctx contractapi.TransactionContextInterface, _ string) error {
+			_, err := s.SuspendOrganization(ctx, testBatchID)
+			return err
+		}},
+		{"DispenseDrug with a product ID", testPharmacyID, drugsID(testProductID, nil), func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.DispenseDrug(ctx, req)
+			return err
+		}},
+		{"DecommissionDrug with a product ID", testManufacturerID, drugsID(testProductID, decommission), func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.DecommissionDrug(ctx, req)
+			return err
+		}},
+		{"DecommissionDrug with a forged drug key", testManufacturerID, drugsID(forgedDrugID, decommission), func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.DecommissionDrug(ctx, req)
+			return err
+		}},
+		{"Aggregate with a batch ID", testManufacturerID, drugsID(testBatchID, aggregate), func(ctx contractapi.TransactionContextInterface, req string) error {
+			_, err := s.Aggregate(ctx, req)
+			return err
+		}},
+		{"ConfirmDecommission with a hold ID", testRegulatorID, nil, func(ctx contractapi.TransactionContextInterface, _ string) error {
+			_, err := s.ConfirmDecommission(ctx, testHoldID)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger(t)
+			stub.put(forgedDrugID, model.Product{DocType: model.DocTypeProduct, ID: forgedDrugID, OwnerID: testManufacturerID})
+
+			var req string
+			if tt.req != nil {
+				req = tt.req(t)
+			}
+			writes, err := stub.invoke(tt.callerID, func(ctx contractapi.TransactionContextInterface) error {
+				return tt.invoke(ctx, req)
+			})
+			if err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if len(writes) != 0 {
+				t.Errorf("expected no writes before the error %q, got %v", err, writes)
+			}
+			if len(stub.events) != 0 {
+				t.Errorf("expected no events, got %d", len(stub.events))
+			}
+		})
+	}
+}
+
+func TestDecommissionRequiresWitnessConfirmation(t *testing.T) {
+	s := new(SmartContract)
+	stub := newLedger(t)
+
+	var decommission *model.Decommission
+	_, err := stub.invoke(testManufacturerID, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		decommission, err = s.DecommissionDrug(ctx, mustJSON(t, map[string]interface{}{
+			"DrugsID":    []string{testDrugID},
+			"Kind":       model.DecommissionKindDestroyed,
+			"ReasonCode": model.DecommissionReasonExpired,
+			"WitnessID":  testRegulatorID,
+		}))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("DecommissionDrug failed: %v", err)
+	}
+	if decommission.Status != model.DecommissionStatusPending {
+		t.Errorf("expected status %s, got %s", model.DecommissionStatusPending, decommission.Status)
+	}
+	if !strings.HasPrefix(decommission.ID, decommissionKey) {
+		t.Errorf("expected a decommission ID, got %s", decommission.ID)
+	}
+	assertEventType(t, stub, event.TypeDecommissionRequested)
+
+	var drug model.Drug
+	stub.get(testDrugID, &drug)
+	if drug.IsDecommissioned || drug.DecommissionID != decommission.ID {
+		t.Errorf("expected drug locked by %s and not decommissioned, got %+v", decommission.ID, drug)
+	}
+	if !stub.hasIndex(ownerDrugIndex, testManufacturerID, testDrugID) {
+		t.Error("expected the drug to stay in its owner's stock while pending")
+	}
+
+	// Neither the owner nor a third party can stand in for the witness.
+	for _, callerID := range []string{testManufacturerID, testPharmacyID} {
+		writes, err := stub.invoke(callerID, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := s.ConfirmDecommission(ctx, decommission.ID)
+			return err
+		})
+		if err == nil {
+			t.Errorf("expected %s to be refused as witness", callerID)
+		}
+		if len(writes) != 0 {
+			t.Errorf("expected no writes, got %v", writes)
+		}
+	}
+
+	_, err = stub.invoke(testRegulatorID, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		decommission, err = s.ConfirmDecommission(ctx, decommission.ID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("ConfirmDecommission failed: %v", err)
+	}
+	if decommission.Status != model.DecommissionStatusConfirmed || decommission.DecommissionedAt.IsZero() {
+		t.Errorf("expected a confirmed decommission, got %+v", decommission)
+	}
+	assertEventType(t, stub, event.TypeDrugDecommissioned)
+
+	stub.get(testDrugID, &drug)
+	if !drug.IsDecommissioned {
+		t.Error("expected the drug to be decommissioned")
+	}
+	if stub.hasIndex(ownerDrugIndex, testManufacturerID, testDrugID) {
+		t.Error("expected the drug to leave its owner's stock")
+	}
+}
+
+func TestRejectDecommissionUnlocksDrugs(t *testing.T) {
+	s := new(SmartContract)
+	stub := newLedger(t)
+
+	var decommission *model.Decommission
+	_, err := stub.invoke(testManufacturerID, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		decommission, err = s.DecommissionDrug(ctx, mustJSON(t, map[string]interface{}{
+			"DrugsID":    []string{testDrugID},
+			"Kind":       model.DecommissionKindStolen,
+			"ReasonCode": model.DecommissionReasonTheft,
+			"WitnessID":  testRegulatorID,
+		}))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("DecommissionDrug failed: %v", err)
+	}
+
+	_, err = stub.invoke(testRegulatorID, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.RejectDecommission(ctx, decommission.ID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("RejectDecommission failed: %v", err)
+	}
+	assertEventType(t, stub, event.TypeDecommissionRejected)
+
+	var drug model.Drug
+	stub.get(testDrugID, &drug)
+	if drug.IsDecommissioned || drug.DecommissionID != "" {
+		t.Errorf("expected the drug to be unlocked, got %+v", drug)
+	}
+
+	var stored model.Decommission
+	stub.get(decommission.ID, &stored)
+	if stored.Status != model.DecommissionStatusRejected {
+		t.Errorf("expected status %s, got %s", model.DecommissionStatusRejected, stored.Status)
+	}
+
+	_, err = stub.invoke(testRegulatorID, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := s.ConfirmDecommission(ctx, decommission.ID)
+		return err
+	})
+	if err == nil {
+		t.Error("expected a rejected decommission to stay rejected")
+	}
+}
+
+func TestReleaseHold(t *testing.T) {
+	s := new(SmartContract)
+
+	tests := []struct {
+		name     string
+		callerID string
+		wantErr  bool
+	}{
+		{"regulator", testRegulatorID, false},
+		{"not the issuer", testManufacturerID, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger(t)
+
+			writes, err := stub.invoke(tt.callerID, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := s.ReleaseHold(ctx, testHoldID)
+				return err
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReleaseHold() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(writes) != 0 {
+					t.Errorf("expected no writes, got %v", writes)
+				}
+				return
+			}
+
+			var hold model.Hold
+			stub.get(testHoldID, &hold)
+			if hold.IsActive || hold.ReleasedBy != tt.callerID {
+				t.Errorf("expected hold released by %s, got %+v", tt.callerID, hold)
+			}
+			if stub.hasIndex(activeHoldIndex, hold.Scope, hold.TargetID, hold.ID) {
+				t.Error("expected the active hold index entry to be removed")
+			}
+			assertEventType(t, stub, event.TypeHoldReleased)
+		})
+	}
+}
+
+func TestRecallBatch(t *testing.T) {
+	s := new(SmartContract)
+
+	tests := []struct {
+		name     string
+		callerID string
+		severity string
+		wantErr  bool
+	}{
+		{"manufacturer", testManufacturerID, model.RecallSeverityClassII, false},
+		{"regulator", testRegulatorID, model.RecallSeverityClassI, false},
+		{"pharmacy", testPharmacyID, model.RecallSeverityClassI, true},
+		{"invalid severity", testRegulatorID, "Class IV", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newLedger(t)
+
+			writes, err := stub.invoke(tt.callerID, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := s.RecallBatch(ctx, testBatchID, "Contamination", tt.severity)
+				return err
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecallBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(writes) != 0 {
+					t.Errorf("expected no writes, got %v", writes)
+				}
+				return
+			}
+
+			var batch model.Batch
+			stub.get(testBatchID, &batch)
+			if !batch.IsRecalled || batch.RecalledBy != tt.callerID || batch.RecallSeverity != tt.severity {
+				t.Errorf("expected batch recalled by %s, got %+v", tt.callerID, batch)
+			}
+		})
+	}
+}
+
+func assertEventType(t *testing.T, stub *testStub, eventType string) {
+	t.Helper()
+
+	payload, ok := stub.events[event.Name]
+	if !ok {
+		t.Fatalf("expected a %s event, got none", eventType)
+	}
+
+	var envelope event.Envelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		t.Fatalf("failed to unmarshal event: %v", err)
+	}
+	if len(envelope.Events) != 1 || envelope.Events[0].Type != eventType {
+		t.Errorf("expected a single %s event, got %+v", eventType, envelope.Events)
+	}
+}
@@ -0,0 +1,299 @@
+package chaincode
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const compositeKeyNamespace = "\x00"
+
+// testTxTime is the ledger time of every transaction run against a testStub.
+var testTxTime = time.Date(2026, time.March, 1, 9, 30, 0, 0, time.UTC)
+
+// testStub is an in-memory world state covering the stub calls the write paths
+// make. Methods it does not implement panic through the nil embedded interface,
+// so a test fails loudly when a transaction reaches for something unexpected.
+type testStub struct {
+	shim.ChaincodeStubInterface
+
+	t       *testing.T
+	creator []byte
+	events  map[string][]byte
+	state   map[string][]byte
+	txCount int
+	writes  []string // Keys written or deleted since the last reset
+}
+
+func newTestStub(t *testing.T) *testStub {
+	return &testStub{
+		t:      t,
+		events: make(map[string][]byte),
+		state:  make(map[string][]byte),
+	}
+}
+
+// invoke runs fn as a transaction submitted by orgID and returns the keys it wrote.
+func (s *testStub) invoke(orgID string, fn func(ctx contractapi.TransactionContextInterface) error) ([]string, error) {
+	s.t.Helper()
+
+	s.creator = testCreator(s.t, orgID+"MSP")
+	s.writes = nil
+	s.txCount++
+
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(s)
+	err := fn(ctx)
+
+	return s.writes, err
+}
+
+func (s *testStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *testStub) GetTxID() string {
+	return fmt.Sprintf("%064x", s.txCount)
+}
+
+func (s *testStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(testTxTime.Add(time.Duration(s.txCount) * time.Second)), nil
+}
+
+func (s *testStub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *testStub) PutState(key string, value []byte) error {
+	s.state[key] = value
+	s.writes = append(s.writes, key)
+	return nil
+}
+
+func (s *testStub) DelState(key string) error {
+	delete(s.state, key)
+	s.writes = append(s.writes, key)
+	return nil
+}
+
+func (s *testStub) SetEvent(name string, payload []byte) error {
+	s.events[name] = payload
+	return nil
+}
+
+func (s *testStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func (s *testStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(compositeKey, compositeKeyNamespace), compositeKeyNamespace), compositeKeyNamespace)
+	return parts[0], parts[1:], nil
+}
+
+func (s *testStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	for key := range s.state {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	results := make([]*queryresult.KV, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	return &testIterator{results: results}, nil
+}
+
+type testIterator struct {
+	results []*queryresult.KV
+}
+
+func (i *testIterator) HasNext() bool {
+	return len(i.results) > 0
+}
+
+func (i *testIterator) Next() (*queryresult.KV, error) {
+	if len(i.results) == 0 {
+		return nil, fmt.Errorf("iterator exhausted")
+	}
+	next := i.results[0]
+	i.results = i.results[1:]
+	return next, nil
+}
+
+func (i *testIterator) Close() error {
+	return nil
+}
+
+// put stores a record as JSON, bypassing the write log.
+func (s *testStub) put(key string, record interface{}) {
+	s.t.Helper()
+
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		s.t.Fatalf("failed to marshal %s: %v", key, err)
+	}
+	s.state[key] = recordJSON
+}
+
+// get decodes a stored record into record.
+func (s *testStub) get(key string, record interface{}) {
+	s.t.Helper()
+
+	recordJSON, ok := s.state[key]
+	if !ok {
+		s.t.Fatalf("%s is not in the world state", key)
+	}
+	if err := json.Unmarshal(recordJSON, record); err != nil {
+		s.t.Fatalf("failed to unmarshal %s: %v", key, err)
+	}
+}
+
+// putIndex stores a composite index entry, bypassing the write log.
+func (s *testStub) putIndex(index string, attributes ...string) {
+	s.t.Helper()
+
+	key, err := shim.CreateCompositeKey(index, attributes)
+	if err != nil {
+		s.t.Fatalf("failed to create composite key: %v", err)
+	}
+	s.state[key] = []byte{0x00}
+}
+
+func (s *testStub) hasIndex(index string, attributes ...string) bool {
+	key, err := shim.CreateCompositeKey(index, attributes)
+	if err != nil {
+		s.t.Fatalf("failed to create composite key: %v", err)
+	}
+	_, ok := s.state[key]
+	return ok
+}
+
+func testCreator(t *testing.T, mspID string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		NotAfter:     testTxTime.AddDate(1, 0, 0),
+		NotBefore:    testTxTime.AddDate(-1, 0, 0),
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: mspID},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		Mspid:   mspID,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal identity: %v", err)
+	}
+	return creator
+}
+
+// Fixture IDs shared by the chaincode tests.
+const (
+	testManufacturerID = "Org1"
+	testPharmacyID     = "Org2"
+	testRegulatorID    = "Org3"
+	testBatchID        = "B0000000000000000001"
+	testDrugID         = "D0000000000000000001"
+	testProductID      = "P0000000000000000001"
+	testHoldID         = "H0000000000000000001"
+	testGTIN           = "09506000134352"
+)
+
+// newLedger seeds a manufacturer, a pharmacy and a regulator, a product and one of
+// its batches with a single drug held by the manufacturer, and an active hold the
+// regulator placed on another batch.
+func newLedger(t *testing.T) *testStub {
+	stub := newTestStub(t)
+
+	for _, org := range []model.Organization{
+		{ID: testManufacturerID, Name: "Kimia Farma", Type: model.OrgTypeManufacturer, Status: model.OrgStatusActive},
+		{ID: testPharmacyID, Name: "Apotek Sehat", Type: model.OrgTypePharmacy, Status: model.OrgStatusActive},
+		{ID: testRegulatorID, Name: "BPOM", Type: model.OrgTypeRegulator, Status: model.OrgStatusActive},
+	} {
+		org.DocType = model.DocTypeOrganization
+		stub.put(org.ID, org)
+	}
+
+	stub.put(testProductID, model.Product{
+		DocType:                      model.DocTypeProduct,
+		DosageForm:                   "Tablet",
+		GenericName:                  "Paracetamol",
+		GTIN:                         testGTIN,
+		ID:                           testProductID,
+		MarketingAuthorizationNumber: "DBL1234567890A1",
+		OwnerID:                      testManufacturerID,
+		PackSize:                     10,
+		Strength:                     "500 mg",
+	})
+	stub.put(testBatchID, model.Batch{
+		DocType:          model.DocTypeBatch,
+		DrugName:         "Paracetamol",
+		ExpiryDate:       testTxTime.AddDate(2, 0, 0),
+		GTIN:             testGTIN,
+		ID:               testBatchID,
+		LotNumber:        "LOT1",
+		ManufacturerID:   testManufacturerID,
+		ManufacturerName: "Kimia Farma",
+		ProductID:        testProductID,
+		ProductionDate:   testTxTime.AddDate(0, -1, 0),
+	})
+	stub.put(testDrugID, model.Drug{
+		BatchID:      testBatchID,
+		DocType:      model.DocTypeDrug,
+		GTIN:         testGTIN,
+		ID:           testDrugID,
+		OwnerID:      testManufacturerID,
+		SerialNumber: "SER1",
+	})
+	stub.putIndex(ownerDrugIndex, testManufacturerID, testDrugID)
+	stub.putIndex(batchDrugIndex, testBatchID, testDrugID)
+
+	stub.put(testHoldID, model.Hold{
+		DocType:  model.DocTypeHold,
+		ID:       testHoldID,
+		IsActive: true,
+		IssuerID: testRegulatorID,
+		Reason:   "Suspected contamination",
+		Scope:    model.HoldScopeBatch,
+		TargetID: "B0000000000000000002",
+	})
+	stub.putIndex(activeHoldIndex, model.HoldScopeBatch, "B0000000000000000002", testHoldID)
+
+	return stub
+}
@@ -0,0 +1,100 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const (
+	timestampToleranceKey = "Config_TimestampTolerance"
+
+	defaultTimestampTolerance = 15 * time.Minute
+)
+
+// SetTimestampTolerance sets how many seconds a client-declared transfer or
+// receive date may differ from the ledger time.
+func (s *SmartContract) SetTimestampTolerance(ctx contractapi.TransactionContextInterface, seconds int) (int, error) {
+	if err := s.assertAdmin(ctx); err != nil {
+		return 0, err
+	}
+	if seconds <= 0 {
+		return 0, fmt.Errorf("timestamp tolerance must be positive")
+	}
+
+	toleranceJSON, err := json.Marshal(seconds)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal timestamp tolerance: %w", err)
+	}
+	if err := ctx.GetStub().PutState(timestampToleranceKey, toleranceJSON); err != nil {
+		return 0, fmt.Errorf("failed to put timestamp tolerance to world state: %w", err)
+	}
+
+	if err := s.emitEvent(ctx, event.TypeTimestampToleranceUpdated, event.TimestampToleranceUpdated{
+		ToleranceSeconds: seconds,
+	}); err != nil {
+		return 0, err
+	}
+
+	return seconds, nil
+}
+
+func (s *SmartContract) GetTimestampTolerance(ctx contractapi.TransactionContextInterface) (int, error) {
+	toleranceJSON, err := ctx.GetStub().GetState(timestampToleranceKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if toleranceJSON == nil {
+		return int(defaultTimestampTolerance / time.Second), nil
+	}
+
+	var seconds int
+	if err := json.Unmarshal(toleranceJSON, &seconds); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal timestamp tolerance: %w", err)
+	}
+
+	return seconds, nil
+}
+
+// checkDeclaredTime rejects a client-declared time that is further from the
+// ledger time than the configured tolerance. A nil declared time is accepted.
+func (s *SmartContract) checkDeclaredTime(ctx contractapi.TransactionContextInterface, field string, declared *time.Time, ledgerTime time.Time) error {
+	if declared == nil {
+		return nil
+	}
+
+	seconds, err := s.GetTimestampTolerance(ctx)
+	if err != nil {
+		return err
+	}
+	tolerance := time.Duration(seconds) * time.Second
+
+	drift := declared.Sub(ledgerTime)
+	if drift > tolerance || drift < -tolerance {
+		return fmt.Errorf("declared %s %s is more than %s from the ledger time %s", field, declared.Format(time.RFC3339), tolerance, ledgerTime.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// stampReceiveDate records the ledger time as the transfer's receive date and
+// keeps the receiver's declared date alongside it.
+func (s *SmartContract) stampReceiveDate(ctx contractapi.TransactionContextInterface, transfer *model.Transfer, declared *time.Time) error {
+	receiveDate, err := s.getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.checkDeclaredTime(ctx, "receive date", declared, receiveDate); err != nil {
+		return err
+	}
+
+	transfer.DeclaredReceiveDate = declared
+	transfer.ReceiveDate = receiveDate
+
+	return nil
+}
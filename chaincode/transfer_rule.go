@@ -0,0 +1,123 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/dto"
+	"github.com/AryaJayadi/MedTrace_chaincode/event"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+const transferRulesKey = "Config_TransferRules"
+
+func (s *SmartContract) SetTransferRules(ctx contractapi.TransactionContextInterface, req string) ([]*model.TransferRule, error) {
+	if err := s.assertAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	var rules dto.SetTransferRules
+	if err := dto.Decode(req, &rules); err != nil {
+		return nil, err
+	}
+
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transfer rules: %w", err)
+	}
+	if err := ctx.GetStub().PutState(transferRulesKey, rulesJSON); err != nil {
+		return nil, fmt.Errorf("failed to put transfer rules to world state: %w", err)
+	}
+
+	if err := s.emitEvent(ctx, event.TypeTransferRulesUpdated, event.TransferRulesUpdated{
+		RuleCount: len(rules),
+	}); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (s *SmartContract) GetTransferRules(ctx contractapi.TransactionContextInterface) ([]*model.TransferRule, error) {
+	rulesJSON, err := ctx.GetStub().GetState(transferRulesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if rulesJSON == nil {
+		return model.DefaultTransferRules(), nil
+	}
+
+	var rules []*model.TransferRule
+	if err := json.Unmarshal(rulesJSON, &rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transfer rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// checkTransferRoute finds the rule that allows the route. Return routes are only
+// open to returns and forward routes only to forward transfers.
+func (s *SmartContract) checkTransferRoute(ctx contractapi.TransactionContextInterface, sender *model.Organization, receiverID string, isReturn bool) (*model.TransferRule, error) {
+	violation := &model.TransferRuleViolation{
+		ReceiverID: receiverID,
+		SenderID:   sender.ID,
+		SenderType: sender.Type,
+	}
+
+	if receiverID == "" {
+		violation.Code = model.ViolationReceiverMissing
+		violation.Message = "receiver ID must be provided"
+		return nil, violation
+	}
+	if receiverID == sender.ID {
+		violation.Code = model.ViolationSelfTransfer
+		violation.Message = fmt.Sprintf("organization %s cannot transfer to itself", sender.ID)
+		return nil, violation
+	}
+
+	exists, err := s.organizationExists(ctx, receiverID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		violation.Code = model.ViolationReceiverNotFound
+		violation.Message = fmt.Sprintf("receiver %s does not exist", receiverID)
+		return nil, violation
+	}
+
+	receiver, err := s.GetOrganization(ctx, receiverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receiver: %w", err)
+	}
+	violation.ReceiverType = receiver.Type
+
+	if receiver.Status == model.OrgStatusSuspended {
+		violation.Code = model.ViolationReceiverSuspended
+		violation.Message = fmt.Sprintf("receiver %s is suspended", receiverID)
+		return nil, violation
+	}
+
+	rules, err := s.GetTransferRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule.SenderType != sender.Type || rule.ReceiverType != receiver.Type {
+			continue
+		}
+		if rule.IsReturn == isReturn {
+			return rule, nil
+		}
+		if rule.IsReturn {
+			violation.Code = model.ViolationReturnRouteOnly
+			violation.Message = fmt.Sprintf("transfers from %s to %s are only allowed as returns", sender.Type, receiver.Type)
+			return nil, violation
+		}
+	}
+
+	violation.Code = model.ViolationRouteNotAllowed
+	violation.Message = fmt.Sprintf("transfers from %s to %s are not allowed", sender.Type, receiver.Type)
+	return nil, violation
+}
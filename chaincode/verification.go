@@ -0,0 +1,160 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/gs1"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// VerifyDrug answers whether a scanned pack is genuine without revealing who holds
+// it. The identifier is either a GS1 DataMatrix code or a MedTrace drug ID.
+func (s *SmartContract) VerifyDrug(ctx contractapi.TransactionContextInterface, identifier string) (*model.DrugVerification, error) {
+	verifiedAt, err := s.getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &model.DrugVerification{
+		Verdict:    model.VerdictUnknown,
+		VerifiedAt: verifiedAt,
+	}
+
+	drug, sgtin, err := s.findDrugByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if drug == nil {
+		verification.Reason = "no drug is registered under this identifier"
+		return verification, nil
+	}
+
+	batch, err := s.GetBatch(ctx, drug.BatchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	verification.DrugName = batch.DrugName
+	verification.ExpiryDate = batch.ExpiryDate
+	verification.GTIN = drug.GTIN
+	verification.LotNumber = batch.LotNumber
+	verification.ManufacturerName = batch.ManufacturerName
+	verification.SerialNumber = drug.SerialNumber
+
+	verdict, reason, err := s.drugVerdict(ctx, drug, batch, sgtin, verifiedAt)
+	if err != nil {
+		return nil, err
+	}
+	verification.Verdict = verdict
+	verification.Reason = reason
+
+	return verification, nil
+}
+
+// drugVerdict picks the most severe verdict that applies to the drug, checking a
+// cloned code or scan alerts first, then decommissioning and safety (recall),
+// then lifecycle state.
+func (s *SmartContract) drugVerdict(ctx contractapi.TransactionContextInterface, drug *model.Drug, batch *model.Batch, sgtin *gs1.SGTIN, now time.Time) (string, string, error) {
+	if sgtin != nil {
+		if sgtin.LotNumber != "" && sgtin.LotNumber != batch.LotNumber {
+			return model.VerdictSuspectedDuplicate, "the lot number on the pack does not match the registered lot", nil
+		}
+		if sgtin.ExpiryDate != nil && !sameDate(*sgtin.ExpiryDate, batch.ExpiryDate) {
+			return model.VerdictSuspectedDuplicate, "the expiry date on the pack does not match the registered expiry date", nil
+		}
+	}
+
+	if drug.IsFlagged {
+		return model.VerdictSuspectedDuplicate, "scans of this pack have raised counterfeit alerts", nil
+	}
+
+	if drug.IsDecommissioned {
+		if drug.DecommissionID != "" {
+			decommission, err := s.GetDecommission(ctx, drug.DecommissionID)
+			if err != nil {
+				return "", "", err
+			}
+			if decommission.Kind == model.DecommissionKindStolen {
+				return model.VerdictDecommissioned, "the pack was reported stolen", nil
+			}
+		}
+		return model.VerdictDecommissioned, "the pack was taken out of the supply chain", nil
+	}
+
+	if drug.IsRecalled || batch.IsRecalled {
+		return model.VerdictRecalled, fmt.Sprintf("the batch was recalled: %s", batch.RecallReason), nil
+	}
+
+	if isExpired(batch, now) {
+		return model.VerdictExpired, "the pack is past its expiry date", nil
+	}
+
+	dispensed, err := s.isDispensed(ctx, drug)
+	if err != nil {
+		return "", "", err
+	}
+	if dispensed {
+		return model.VerdictDispensed, "the pack has already been dispensed to a patient", nil
+	}
+
+	return model.VerdictGenuine, "", nil
+}
+
+// isDispensed also treats drugs owned by a Patient organization as dispensed, as
+// pharmacies used to dispense by transferring to one.
+func (s *SmartContract) isDispensed(ctx contractapi.TransactionContextInterface, drug *model.Drug) (bool, error) {
+	if drug.IsDispensed {
+		return true, nil
+	}
+
+	owner, err := s.GetOrganization(ctx, drug.OwnerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get drug owner: %w", err)
+	}
+
+	return owner.Type == model.OrgTypePatient, nil
+}
+
+// findDrugByIdentifier returns a nil drug when the identifier is not registered.
+// The parsed GS1 code is returned as well so its lot and expiry can be checked.
+func (s *SmartContract) findDrugByIdentifier(ctx contractapi.TransactionContextInterface, identifier string) (*model.Drug, *gs1.SGTIN, error) {
+	sgtin, err := gs1.Parse(identifier)
+	if err == nil {
+		drugID, err := s.findDrugIDBySGTIN(ctx, sgtin.GTIN, sgtin.Serial)
+		if err != nil {
+			return nil, nil, err
+		}
+		if drugID == "" {
+			return nil, sgtin, nil
+		}
+
+		drug, err := s.GetDrug(ctx, drugID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return drug, sgtin, nil
+	}
+
+	if !strings.HasPrefix(identifier, drugKey) {
+		return nil, nil, nil
+	}
+
+	drugJSON, err := ctx.GetStub().GetState(identifier)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if drugJSON == nil {
+		return nil, nil, nil
+	}
+
+	var drug model.Drug
+	if err := json.Unmarshal(drugJSON, &drug); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal drug: %w", err)
+	}
+	return &drug, nil, nil
+}
@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/gs1"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+)
+
+type Aggregate struct {
+	ContainerID  string   `json:"ContainerID"`  // SSCC of the case or pallet
+	ContainersID []string `json:"ContainersID"` // SSCCs of the cases to pack onto a pallet
+	DrugsID      []string `json:"DrugsID"`      // IDs of the drugs to pack into a case
+	Level        string   `json:"Level"`        // Packaging level (Case, Pallet)
+}
+
+// Validate checks the packing rules that do not depend on ledger state. Drugs
+// and containers share one list of IDs, so neither may be listed twice.
+func (a *Aggregate) Validate() error {
+	validation := &ValidationError{}
+	if err := gs1.ValidateSSCC(a.ContainerID); err != nil {
+		validation.add("ContainerID", CodeInvalid, "%v", err)
+	}
+	if !model.IsValidContainerLevel(a.Level) {
+		validation.add("Level", CodeInvalid, "invalid container level %q", a.Level)
+	}
+	if a.Level == model.ContainerLevelCase && len(a.ContainersID) > 0 {
+		validation.add("ContainersID", CodeInvalid, "cases can only hold drugs")
+	}
+	if a.Level == model.ContainerLevelPallet && len(a.DrugsID) > 0 {
+		validation.add("DrugsID", CodeInvalid, "pallets can only hold cases")
+	}
+	if len(a.DrugsID) == 0 && len(a.ContainersID) == 0 {
+		validation.add("DrugsID", CodeRequired, "at least one drug or container must be packed")
+	}
+
+	seen := make(map[string]bool)
+	for i, drugID := range a.DrugsID {
+		checkID(validation, seen, fmt.Sprintf("DrugsID[%d]", i), drugID)
+	}
+	for i, containerID := range a.ContainersID {
+		field := fmt.Sprintf("ContainersID[%d]", i)
+		checkID(validation, seen, field, containerID)
+		if containerID == a.ContainerID {
+			validation.add(field, CodeInvalid, "container %s cannot be packed into itself", containerID)
+		} else if err := gs1.ValidateSSCC(containerID); containerID != "" && err != nil {
+			validation.add(field, CodeInvalid, "%v", err)
+		}
+	}
+
+	return validation.err()
+}
@@ -2,12 +2,96 @@ package dto
 
 import (
 	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/gs1"
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
 )
 
 type CreateBatch struct {
-	Amount         int       `json:"Amount"`         // Amount of drugs in the batch
-	DrugName       string    `json:"DrugName"`       // Drug name
-	ExpiryDate     time.Time `json:"ExpiryDate"`     // Expiry date for all drugs in the batch
-	ID             string    `json:"ID"`             // Unique batch ID
-	ProductionDate time.Time `json:"ProductionDate"` // Production date for all drugs in the batch
+	Amount            int                      `json:"Amount"`            // Amount of drugs in the batch
+	DrugName          string                   `json:"DrugName"`          // Drug name
+	ExpiryDate        time.Time                `json:"ExpiryDate"`        // Expiry date for all drugs in the batch
+	GTIN              string                   `json:"GTIN"`              // Optional GS1 GTIN, enables SGTIN serialization
+	ID                string                   `json:"ID"`                // Unique batch ID
+	LotNumber         string                   `json:"LotNumber"`         // GS1 lot number, required with GTIN
+	ProductID         string                   `json:"ProductID"`         // Optional product ID, supplies the drug name and GTIN
+	ProductionDate    time.Time                `json:"ProductionDate"`    // Production date for all drugs in the batch
+	SerialNumbers     []string                 `json:"SerialNumbers"`     // Optional serial numbers, one per drug; generated when empty
+	StorageConditions *model.StorageConditions `json:"StorageConditions"` // Optional storage ranges for cold-chain products
+}
+
+// maxBatchAmount keeps drug sequence numbers within the 8 digits chaincode pads
+// them to, so drug IDs of a batch sort in sequence order.
+const maxBatchAmount = 99999999
+
+func (c *CreateBatch) Validate() error {
+	validation := &ValidationError{}
+	if c.Amount < 1 || c.Amount > maxBatchAmount {
+		validation.add("Amount", CodeOutOfRange, "amount must be between 1 and %d, got %d", maxBatchAmount, c.Amount)
+	}
+	if c.DrugName == "" && c.ProductID == "" {
+		validation.add("DrugName", CodeRequired, "drug name must be provided when no product ID is given")
+	}
+	if (c.GTIN != "" || c.ProductID != "") && c.LotNumber == "" {
+		validation.add("LotNumber", CodeRequired, "lot number must be provided for serialized batches")
+	}
+	checkBatchDates(validation, c.ProductionDate, c.ExpiryDate)
+	checkStorageConditions(validation, c.StorageConditions)
+
+	if len(c.SerialNumbers) > 0 {
+		if c.Amount >= 1 && len(c.SerialNumbers) != c.Amount {
+			validation.add("SerialNumbers", CodeMismatch, "got %d serial numbers for %d drugs", len(c.SerialNumbers), c.Amount)
+		}
+		checkIDs(validation, "SerialNumbers", c.SerialNumbers)
+	}
+
+	return validation.err()
+}
+
+// ValidateProduct checks that a drug name or GTIN given alongside a product ID
+// matches the product, which supplies both for the batch.
+func (c *CreateBatch) ValidateProduct(product *model.Product) error {
+	validation := &ValidationError{}
+	if c.DrugName != "" && c.DrugName != product.GenericName {
+		validation.add("DrugName", CodeMismatch, "drug name %q does not match product %s generic name %q", c.DrugName, product.ID, product.GenericName)
+	}
+	if c.GTIN != "" {
+		if gtin, err := gs1.NormalizeGTIN(c.GTIN); err != nil {
+			validation.add("GTIN", CodeInvalid, "%v", err)
+		} else if gtin != product.GTIN {
+			validation.add("GTIN", CodeMismatch, "GTIN %s does not match product %s GTIN %s", gtin, product.ID, product.GTIN)
+		}
+	}
+
+	return validation.err()
+}
+
+func checkStorageConditions(validation *ValidationError, conditions *model.StorageConditions) {
+	if conditions == nil {
+		return
+	}
+
+	checkConditionRange(validation, model.ConditionTemperature, conditions.Temperature)
+	checkConditionRange(validation, model.ConditionHumidity, conditions.Humidity)
+	if conditions.Humidity != nil && (conditions.Humidity.Min < 0 || conditions.Humidity.Max > 100) {
+		validation.add("StorageConditions.Humidity", CodeOutOfRange, "humidity range must be within 0 and 100 percent")
+	}
+}
+
+func checkConditionRange(validation *ValidationError, condition string, allowed *model.ConditionRange) {
+	if allowed != nil && allowed.Min > allowed.Max {
+		validation.add("StorageConditions."+condition, CodeInvalidOrder, "%s range minimum %v is above its maximum %v", condition, allowed.Min, allowed.Max)
+	}
+}
+
+func checkBatchDates(validation *ValidationError, productionDate time.Time, expiryDate time.Time) {
+	if productionDate.IsZero() {
+		validation.add("ProductionDate", CodeRequired, "production date must be provided")
+	}
+	if expiryDate.IsZero() {
+		validation.add("ExpiryDate", CodeRequired, "expiry date must be provided")
+	}
+	if !productionDate.IsZero() && !expiryDate.IsZero() && !expiryDate.After(productionDate) {
+		validation.add("ExpiryDate", CodeInvalidOrder, "expiry date %s must be after production date %s", expiryDate.Format("2006-01-02"), productionDate.Format("2006-01-02"))
+	}
 }
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+)
+
+type CreateReturn struct {
+	Disposition        string     `json:"Disposition"`        // Whether the returned units can be resold (Saleable, NonSaleable)
+	DrugsID            []string   `json:"DrugsID"`            // IDs of the drugs to return
+	OriginalTransferID string     `json:"OriginalTransferID"` // ID of the forward transfer the drugs were received in
+	ReasonCode         string     `json:"ReasonCode"`         // Return reason code
+	TransferDate       *time.Time `json:"TransferDate"`       // Declared transfer date, optional
+}
+
+func (c *CreateReturn) Validate() error {
+	validation := &ValidationError{}
+	if c.OriginalTransferID == "" {
+		validation.add("OriginalTransferID", CodeRequired, "original transfer ID must be provided")
+	}
+	if !model.IsValidReturnReason(c.ReasonCode) {
+		validation.add("ReasonCode", CodeInvalid, "invalid return reason code %q", c.ReasonCode)
+	}
+	if !model.IsValidReturnDisposition(c.Disposition) {
+		validation.add("Disposition", CodeInvalid, "invalid return disposition %q", c.Disposition)
+	}
+	if len(c.DrugsID) == 0 {
+		validation.add("DrugsID", CodeRequired, "at least one drug must be returned")
+	}
+	checkIDs(validation, "DrugsID", c.DrugsID)
+
+	return validation.err()
+}
@@ -3,8 +3,20 @@ package dto
 import "time"
 
 type CreateTransfer struct {
+	ContainersID []*string  `json:"ContainersID"` // List of container SSCCs whose contents are transferred
 	DrugsID      []*string  `json:"DrugsID"`      // List of drug IDs
 	ReceiverID   *string    `json:"ReceiverID"`   // Receiver ID
 	SenderID     *string    `json:"SenderID"`     // Sender ID
-	TransferDate *time.Time `json:"TransferDate"` // Transfer date
+	TransferDate *time.Time `json:"TransferDate"` // Declared transfer date, optional
+}
+
+func (c *CreateTransfer) Validate() error {
+	validation := &ValidationError{}
+	if len(c.DrugsID) == 0 && len(c.ContainersID) == 0 {
+		validation.add("DrugsID", CodeRequired, "at least one drug or container must be transferred")
+	}
+	checkIDPointers(validation, "DrugsID", c.DrugsID)
+	checkIDPointers(validation, "ContainersID", c.ContainersID)
+
+	return validation.err()
 }
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"encoding/hex"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+)
+
+type DecommissionDrug struct {
+	CertificateHash string   `json:"CertificateHash"` // Hex SHA-256 of the certificate of destruction, optional
+	DrugsID         []string `json:"DrugsID"`         // IDs of the drugs to decommission
+	Kind            string   `json:"Kind"`            // Decommission kind (Destroyed, Sample, Stolen)
+	ReasonCode      string   `json:"ReasonCode"`      // Decommission reason code
+	WitnessID       string   `json:"WitnessID"`       // ID of the witnessing organization
+}
+
+const certificateHashLength = 64
+
+func (d *DecommissionDrug) Validate() error {
+	validation := &ValidationError{}
+	if !model.IsValidDecommissionKind(d.Kind) {
+		validation.add("Kind", CodeInvalid, "invalid decommission kind %q", d.Kind)
+	}
+	if !model.IsValidDecommissionReason(d.ReasonCode) {
+		validation.add("ReasonCode", CodeInvalid, "invalid decommission reason code %q", d.ReasonCode)
+	}
+	if d.WitnessID == "" {
+		validation.add("WitnessID", CodeRequired, "witness ID must be provided")
+	}
+	if len(d.DrugsID) == 0 {
+		validation.add("DrugsID", CodeRequired, "at least one drug must be decommissioned")
+	}
+	checkIDs(validation, "DrugsID", d.DrugsID)
+
+	if d.CertificateHash != "" {
+		if _, err := hex.DecodeString(d.CertificateHash); err != nil || len(d.CertificateHash) != certificateHashLength {
+			validation.add("CertificateHash", CodeInvalid, "certificate hash must be a hex encoded SHA-256 digest")
+		}
+		if d.Kind != model.DecommissionKindDestroyed {
+			validation.add("CertificateHash", CodeInvalid, "a certificate of destruction only applies to destroyed drugs")
+		}
+	}
+
+	return validation.err()
+}
@@ -0,0 +1,17 @@
+package dto
+
+type DispenseDrug struct {
+	DrugsID        []string `json:"DrugsID"`        // IDs of the drugs to dispense
+	PatientRef     string   `json:"PatientRef"`     // Pseudonymous patient reference, optional
+	PrescriptionID string   `json:"PrescriptionID"` // Prescription ID, optional
+}
+
+func (d *DispenseDrug) Validate() error {
+	validation := &ValidationError{}
+	if len(d.DrugsID) == 0 {
+		validation.add("DrugsID", CodeRequired, "at least one drug must be dispensed")
+	}
+	checkIDs(validation, "DrugsID", d.DrugsID)
+
+	return validation.err()
+}
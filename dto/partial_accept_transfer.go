@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+)
+
+type PartialAcceptTransfer struct {
+	OverrideExcursion bool          `json:"OverrideExcursion"` // Receive units despite a cold-chain excursion
+	ReceiveDate       *time.Time    `json:"ReceiveDate"`       // Declared receive date, optional
+	ReceivedDrugsID   []string      `json:"ReceivedDrugsID"`   // IDs of drugs that arrived in good condition
+	RefusedDrugs      []RefusedDrug `json:"RefusedDrugs"`      // Drugs refused by the receiver
+	TransferID        string        `json:"transferID"`        // ID of Transfer to be processed
+}
+
+type RefusedDrug struct {
+	DrugID     string `json:"DrugID"`     // ID of the refused drug
+	ReasonCode string `json:"ReasonCode"` // Refusal reason code (Damaged, ConditionExcursion, Missing, WrongProduct, Other)
+}
+
+// Validate treats received and refused drugs as one list, so a drug cannot be
+// both received and refused.
+func (p *PartialAcceptTransfer) Validate() error {
+	validation := &ValidationError{}
+	if p.TransferID == "" {
+		validation.add("transferID", CodeRequired, "transfer ID must be provided")
+	}
+	if len(p.ReceivedDrugsID) == 0 && len(p.RefusedDrugs) == 0 {
+		validation.add("ReceivedDrugsID", CodeRequired, "every drug of the transfer must be listed as received or refused")
+	}
+
+	seen := make(map[string]bool)
+	for i, drugID := range p.ReceivedDrugsID {
+		checkID(validation, seen, fmt.Sprintf("ReceivedDrugsID[%d]", i), drugID)
+	}
+	for i, refused := range p.RefusedDrugs {
+		checkID(validation, seen, fmt.Sprintf("RefusedDrugs[%d].DrugID", i), refused.DrugID)
+		if !model.IsValidRefusalReason(refused.ReasonCode) {
+			field := fmt.Sprintf("RefusedDrugs[%d].ReasonCode", i)
+			validation.add(field, CodeInvalid, "invalid refusal reason %q", refused.ReasonCode)
+		}
+	}
+
+	return validation.err()
+}
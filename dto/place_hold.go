@@ -0,0 +1,24 @@
+package dto
+
+import "github.com/AryaJayadi/MedTrace_chaincode/model"
+
+type PlaceHold struct {
+	Reason   string `json:"Reason"`   // Reason for the hold
+	Scope    string `json:"Scope"`    // Hold scope (Drug, Batch, Owner)
+	TargetID string `json:"TargetID"` // Drug, batch or organization ID to hold
+}
+
+func (p *PlaceHold) Validate() error {
+	validation := &ValidationError{}
+	if !model.IsValidHoldScope(p.Scope) {
+		validation.add("Scope", CodeInvalid, "invalid hold scope %q", p.Scope)
+	}
+	if p.TargetID == "" {
+		validation.add("TargetID", CodeRequired, "hold target ID must be provided")
+	}
+	if p.Reason == "" {
+		validation.add("Reason", CodeRequired, "hold reason must not be empty")
+	}
+
+	return validation.err()
+}
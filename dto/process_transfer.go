@@ -3,6 +3,16 @@ package dto
 import "time"
 
 type ProcessTransfer struct {
-	ReceiveDate *time.Time `json:"ReceiveDate"` // Receive date
-	TransferID  string     `json:"transferID"`  // ID of Transfer to be processed
+	OverrideExcursion bool       `json:"OverrideExcursion"` // Accept units despite a cold-chain excursion
+	ReceiveDate       *time.Time `json:"ReceiveDate"`       // Declared receive date, optional
+	TransferID        string     `json:"transferID"`        // ID of Transfer to be processed
+}
+
+func (p *ProcessTransfer) Validate() error {
+	validation := &ValidationError{}
+	if p.TransferID == "" {
+		validation.add("transferID", CodeRequired, "transfer ID must be provided")
+	}
+
+	return validation.err()
 }
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+type QueryBatches struct {
+	DrugName       string     `json:"DrugName"`       // Only batches with this drug name
+	ExpiryFrom     *time.Time `json:"ExpiryFrom"`     // Only batches expiring at or after this date
+	ExpiryTo       *time.Time `json:"ExpiryTo"`       // Only batches expiring at or before this date
+	IsRecalled     *bool      `json:"isRecalled"`     // Only recalled or non-recalled batches
+	ManufacturerID string     `json:"ManufacturerID"` // Only batches made by this manufacturer
+	ProductionFrom *time.Time `json:"ProductionFrom"` // Only batches produced at or after this date
+	ProductionTo   *time.Time `json:"ProductionTo"`   // Only batches produced at or before this date
+}
+
+func (q *QueryBatches) Validate() error {
+	validation := &ValidationError{}
+	checkDateRange(validation, "ExpiryTo", q.ExpiryFrom, q.ExpiryTo)
+	checkDateRange(validation, "ProductionTo", q.ProductionFrom, q.ProductionTo)
+
+	return validation.err()
+}
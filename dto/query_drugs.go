@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+type QueryDrugs struct {
+	BatchID       string     `json:"BatchID"`       // Only drugs of this batch
+	DrugName      string     `json:"DrugName"`      // Only drugs whose batch has this drug name
+	ExpiryFrom    *time.Time `json:"ExpiryFrom"`    // Only drugs whose batch expires at or after this date
+	ExpiryTo      *time.Time `json:"ExpiryTo"`      // Only drugs whose batch expires at or before this date
+	IsRecalled    *bool      `json:"isRecalled"`    // Only recalled or non-recalled drugs
+	IsTransferred *bool      `json:"isTransferred"` // Only drugs in or out of a pending transfer
+	OwnerID       string     `json:"OwnerID"`       // Only drugs held by this owner, defaults to the caller; regulators may leave it empty for all owners
+}
+
+func (q *QueryDrugs) Validate() error {
+	validation := &ValidationError{}
+	checkDateRange(validation, "ExpiryTo", q.ExpiryFrom, q.ExpiryTo)
+
+	return validation.err()
+}
+
+// checkDateRange reports an open range whose end is before its start on toField.
+func checkDateRange(validation *ValidationError, toField string, from *time.Time, to *time.Time) {
+	if from != nil && to != nil && to.Before(*from) {
+		validation.add(toField, CodeInvalidOrder, "%s %s must not be before %s", toField, to.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+}
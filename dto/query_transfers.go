@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+)
+
+// QueryTransfers filters the caller's own transfers, or every transfer when the
+// caller is a regulator.
+type QueryTransfers struct {
+	ReceiverID   string     `json:"ReceiverID"`   // Only transfers to this receiver
+	SenderID     string     `json:"SenderID"`     // Only transfers from this sender
+	Status       string     `json:"Status"`       // Only transfers with this status
+	TransferFrom *time.Time `json:"TransferFrom"` // Only transfers sent at or after this date
+	TransferTo   *time.Time `json:"TransferTo"`   // Only transfers sent at or before this date
+}
+
+func (q *QueryTransfers) Validate() error {
+	validation := &ValidationError{}
+	switch q.Status {
+	case "", model.TransferStatusPending, model.TransferStatusAccepted, model.TransferStatusPartiallyAccepted,
+		model.TransferStatusRejected, model.TransferStatusCancelled:
+	default:
+		validation.add("Status", CodeInvalid, "invalid transfer status %q", q.Status)
+	}
+	checkDateRange(validation, "TransferTo", q.TransferFrom, q.TransferTo)
+
+	return validation.err()
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type RecordConditionReading struct {
+	Humidity    *float64   `json:"Humidity"`    // Measured relative humidity, optional
+	MeasuredAt  *time.Time `json:"MeasuredAt"`  // Time the sensor took the reading, defaults to the ledger time
+	SensorID    string     `json:"SensorID"`    // Identifier of the data logger
+	Temperature *float64   `json:"Temperature"` // Measured temperature, optional
+	TransferID  string     `json:"TransferID"`  // ID of the in-flight transfer
+}
+
+func (r *RecordConditionReading) Validate() error {
+	validation := &ValidationError{}
+	if r.TransferID == "" {
+		validation.add("TransferID", CodeRequired, "transfer ID must be provided")
+	}
+	if r.SensorID == "" {
+		validation.add("SensorID", CodeRequired, "sensor ID must not be empty")
+	}
+	if r.Temperature == nil && r.Humidity == nil {
+		validation.add("Temperature", CodeRequired, "a temperature or humidity value must be provided")
+	}
+
+	return validation.err()
+}
@@ -0,0 +1,26 @@
+package dto
+
+type RecordScan struct {
+	DrugID    string   `json:"DrugID"`    // Scanned drug ID
+	Latitude  *float64 `json:"Latitude"`  // Scan latitude in degrees, optional
+	Location  string   `json:"Location"`  // Free-text scan location
+	Longitude *float64 `json:"Longitude"` // Scan longitude in degrees, optional
+}
+
+func (r *RecordScan) Validate() error {
+	validation := &ValidationError{}
+	if r.DrugID == "" {
+		validation.add("DrugID", CodeRequired, "drug ID must be provided")
+	}
+	if (r.Latitude == nil) != (r.Longitude == nil) {
+		validation.add("Latitude", CodeRequired, "latitude and longitude must be provided together")
+	}
+	if r.Latitude != nil && (*r.Latitude < -90 || *r.Latitude > 90) {
+		validation.add("Latitude", CodeOutOfRange, "latitude %v must be between -90 and 90", *r.Latitude)
+	}
+	if r.Longitude != nil && (*r.Longitude < -180 || *r.Longitude > 180) {
+		validation.add("Longitude", CodeOutOfRange, "longitude %v must be between -180 and 180", *r.Longitude)
+	}
+
+	return validation.err()
+}
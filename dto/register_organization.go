@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+)
+
+type RegisterOrganization struct {
+	ID       string `json:"ID"`       // Organization ID, derived from the MSP ID without the "MSP" suffix
+	Location string `json:"Location"` // Organization location
+	Name     string `json:"Name"`     // Organization name
+	Type     string `json:"Type"`     // Organization type
+}
+
+func (r *RegisterOrganization) Validate() error {
+	validation := &ValidationError{}
+	if r.ID == "" {
+		validation.add("ID", CodeRequired, "organization ID must be provided")
+	}
+	checkOrganization(validation, r.Name, r.Type)
+
+	return validation.err()
+}
+
+func checkOrganization(validation *ValidationError, name string, orgType string) {
+	if strings.TrimSpace(name) == "" {
+		validation.add("Name", CodeRequired, "organization name must not be empty")
+	}
+	if !model.IsValidOrgType(orgType) {
+		validation.add("Type", CodeInvalid, "invalid organization type %q", orgType)
+	}
+}
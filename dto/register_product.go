@@ -0,0 +1,40 @@
+package dto
+
+import "github.com/AryaJayadi/MedTrace_chaincode/gs1"
+
+type RegisterProduct struct {
+	DosageForm                   string `json:"DosageForm"`                   // Dosage form
+	GenericName                  string `json:"GenericName"`                  // International non-proprietary name
+	GTIN                         string `json:"GTIN"`                         // GS1 GTIN of the trade item
+	MarketingAuthorizationNumber string `json:"MarketingAuthorizationNumber"` // Marketing authorization number
+	PackSize                     int    `json:"PackSize"`                     // Number of dosage units in one pack
+	Strength                     string `json:"Strength"`                     // Strength
+}
+
+func (r *RegisterProduct) Validate() error {
+	validation := &ValidationError{}
+	if _, err := gs1.NormalizeGTIN(r.GTIN); err != nil {
+		validation.add("GTIN", CodeInvalid, "%v", err)
+	}
+	checkProduct(validation, r.GenericName, r.Strength, r.DosageForm, r.MarketingAuthorizationNumber, r.PackSize)
+
+	return validation.err()
+}
+
+func checkProduct(validation *ValidationError, genericName string, strength string, dosageForm string, marketingAuthorizationNumber string, packSize int) {
+	if genericName == "" {
+		validation.add("GenericName", CodeRequired, "product generic name must not be empty")
+	}
+	if strength == "" {
+		validation.add("Strength", CodeRequired, "product strength must not be empty")
+	}
+	if dosageForm == "" {
+		validation.add("DosageForm", CodeRequired, "product dosage form must not be empty")
+	}
+	if marketingAuthorizationNumber == "" {
+		validation.add("MarketingAuthorizationNumber", CodeRequired, "product marketing authorization number must not be empty")
+	}
+	if packSize <= 0 {
+		validation.add("PackSize", CodeOutOfRange, "product pack size must be positive, got %d", packSize)
+	}
+}
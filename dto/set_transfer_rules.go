@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"fmt"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+)
+
+// SetTransferRules is the complete list of transfer rules that replaces the
+// rules in force.
+type SetTransferRules []*model.TransferRule
+
+func (r *SetTransferRules) Validate() error {
+	validation := &ValidationError{}
+	if len(*r) == 0 {
+		validation.add("", CodeRequired, "at least one transfer rule must be provided")
+	}
+
+	seen := make(map[string]bool)
+	for i, rule := range *r {
+		field := fmt.Sprintf("[%d]", i)
+		if rule == nil {
+			validation.add(field, CodeRequired, "transfer rule %d must not be null", i)
+			continue
+		}
+		if !model.IsValidOrgType(rule.SenderType) {
+			validation.add(field+".SenderType", CodeInvalid, "invalid sender type %q", rule.SenderType)
+		}
+		if !model.IsValidOrgType(rule.ReceiverType) {
+			validation.add(field+".ReceiverType", CodeInvalid, "invalid receiver type %q", rule.ReceiverType)
+		}
+
+		route := rule.SenderType + "->" + rule.ReceiverType
+		if seen[route] {
+			validation.add(field, CodeDuplicate, "transfer rule %s is listed more than once", route)
+		}
+		seen[route] = true
+	}
+
+	return validation.err()
+}
@@ -7,3 +7,13 @@ type UpdateBatch struct {
 	ExpiryDate     time.Time `json:"ExpiryDate"`     // Expiry date for all drugs in the batch
 	ProductionDate time.Time `json:"ProductionDate"` // Production date
 }
+
+func (u *UpdateBatch) Validate() error {
+	validation := &ValidationError{}
+	if u.DrugName == "" {
+		validation.add("DrugName", CodeRequired, "drug name must be provided")
+	}
+	checkBatchDates(validation, u.ProductionDate, u.ExpiryDate)
+
+	return validation.err()
+}
@@ -0,0 +1,14 @@
+package dto
+
+type UpdateOrganization struct {
+	Location string `json:"Location"` // Organization location
+	Name     string `json:"Name"`     // Organization name
+	Type     string `json:"Type"`     // Organization type
+}
+
+func (u *UpdateOrganization) Validate() error {
+	validation := &ValidationError{}
+	checkOrganization(validation, u.Name, u.Type)
+
+	return validation.err()
+}
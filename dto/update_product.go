@@ -0,0 +1,16 @@
+package dto
+
+type UpdateProduct struct {
+	DosageForm                   string `json:"DosageForm"`                   // Dosage form
+	GenericName                  string `json:"GenericName"`                  // International non-proprietary name
+	MarketingAuthorizationNumber string `json:"MarketingAuthorizationNumber"` // Marketing authorization number
+	PackSize                     int    `json:"PackSize"`                     // Number of dosage units in one pack
+	Strength                     string `json:"Strength"`                     // Strength
+}
+
+func (u *UpdateProduct) Validate() error {
+	validation := &ValidationError{}
+	checkProduct(validation, u.GenericName, u.Strength, u.DosageForm, u.MarketingAuthorizationNumber, u.PackSize)
+
+	return validation.err()
+}
@@ -0,0 +1,138 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	CodeMalformed    = "MALFORMED"
+	CodeUnknownField = "UNKNOWN_FIELD"
+	CodeInvalidType  = "INVALID_TYPE"
+	CodeRequired     = "REQUIRED"
+	CodeInvalid      = "INVALID"
+	CodeDuplicate    = "DUPLICATE"
+	CodeOutOfRange   = "OUT_OF_RANGE"
+	CodeInvalidOrder = "INVALID_ORDER"
+	CodeMismatch     = "MISMATCH"
+)
+
+// Validator is implemented by requests that check their own fields after decoding.
+type Validator interface {
+	Validate() error
+}
+
+type FieldError struct {
+	Code    string `json:"Code"`    // Machine-readable error code
+	Field   string `json:"Field"`   // JSON path of the offending field, empty for the whole request
+	Message string `json:"Message"` // Human readable description
+}
+
+// ValidationError collects every problem found in a request. Its Error string is
+// the JSON encoding of the struct so clients can map the errors to form fields.
+type ValidationError struct {
+	Errors []*FieldError `json:"Errors"` // Field errors in the order they were found
+}
+
+func (e *ValidationError) Error() string {
+	errorJSON, err := json.Marshal(e)
+	if err != nil {
+		messages := make([]string, 0, len(e.Errors))
+		for _, fieldError := range e.Errors {
+			messages = append(messages, fieldError.Message)
+		}
+		return strings.Join(messages, "; ")
+	}
+	return string(errorJSON)
+}
+
+func (e *ValidationError) add(field string, code string, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, &FieldError{
+		Code:    code,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns nil when no errors were collected, so callers can return it directly.
+func (e *ValidationError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Decode strictly decodes a JSON request into v, rejecting unknown fields and
+// trailing data, and then runs v's own validation.
+func Decode(req string, v Validator) error {
+	decoder := json.NewDecoder(strings.NewReader(req))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		validation := &ValidationError{}
+		validation.add("", CodeMalformed, "request must contain a single JSON object")
+		return validation
+	}
+
+	return v.Validate()
+}
+
+func decodeError(err error) error {
+	validation := &ValidationError{}
+
+	var typeError *json.UnmarshalTypeError
+	var syntaxError *json.SyntaxError
+	switch {
+	case errors.As(err, &typeError):
+		validation.add(typeError.Field, CodeInvalidType, "%s must be %s, got %s", typeError.Field, typeError.Type, typeError.Value)
+	case errors.As(err, &syntaxError):
+		validation.add("", CodeMalformed, "malformed JSON at offset %d: %v", syntaxError.Offset, err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		validation.add(field, CodeUnknownField, "unknown field %s", field)
+	default:
+		validation.add("", CodeMalformed, "failed to decode request: %v", err)
+	}
+
+	return validation
+}
+
+// checkIDs requires every ID to be present and listed once.
+func checkIDs(validation *ValidationError, field string, ids []string) {
+	seen := make(map[string]bool)
+	for i, id := range ids {
+		checkID(validation, seen, fmt.Sprintf("%s[%d]", field, i), id)
+	}
+}
+
+// checkIDPointers is checkIDs for requests that decode IDs into pointers, where a
+// JSON null would otherwise be dereferenced.
+func checkIDPointers(validation *ValidationError, field string, ids []*string) {
+	seen := make(map[string]bool)
+	for i, id := range ids {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		if id == nil {
+			validation.add(itemField, CodeRequired, "%s must not be null", itemField)
+			continue
+		}
+		checkID(validation, seen, itemField, *id)
+	}
+}
+
+func checkID(validation *ValidationError, seen map[string]bool, field string, id string) {
+	if id == "" {
+		validation.add(field, CodeRequired, "%s must not be empty", field)
+		return
+	}
+	if seen[id] {
+		validation.add(field, CodeDuplicate, "%s is listed more than once", id)
+		return
+	}
+	seen[id] = true
+}
@@ -0,0 +1,190 @@
+package dto
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AryaJayadi/MedTrace_chaincode/model"
+)
+
+const (
+	testSSCC      = "106141411234567897"
+	testChildSSCC = "006141411234567890"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   string
+		code  string
+		field string
+	}{
+		{"valid", `{"DrugsID":["D1"]}`, "", ""},
+		{"unknown field", `{"DrugsID":["D1"],"DrugIDs":["D2"]}`, CodeUnknownField, "DrugIDs"},
+		{"wrong type", `{"DrugsID":"D1"}`, CodeInvalidType, "DrugsID"},
+		{"syntax error", `{"DrugsID":["D1"]`, CodeMalformed, ""},
+		{"invalid JSON", `{"DrugsID":[D1]}`, CodeMalformed, ""},
+		{"trailing object", `{"DrugsID":["D1"]}{"DrugsID":["D2"]}`, CodeMalformed, ""},
+		{"trailing garbage", `{"DrugsID":["D1"]} x`, CodeMalformed, ""},
+		{"empty request", ``, CodeMalformed, ""},
+		{"validation failure", `{"DrugsID":[]}`, CodeRequired, "DrugsID"},
+		{"trailing whitespace", "{\"DrugsID\":[\"D1\"]}\n ", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dispenseDrug DispenseDrug
+			err := Decode(tt.req, &dispenseDrug)
+			assertFirstError(t, err, tt.code, tt.field)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   string
+		v     Validator
+		code  string
+		field string
+	}{
+		{"aggregate case", `{"ContainerID":"` + testSSCC + `","Level":"Case","DrugsID":["D1","D2"]}`, &Aggregate{}, "", ""},
+		{"aggregate pallet", `{"ContainerID":"` + testSSCC + `","Level":"Pallet","ContainersID":["` + testChildSSCC + `"]}`, &Aggregate{}, "", ""},
+		{"aggregate invalid SSCC", `{"ContainerID":"106141411234567890","Level":"Case","DrugsID":["D1"]}`, &Aggregate{}, CodeInvalid, "ContainerID"},
+		{"aggregate invalid level", `{"ContainerID":"` + testSSCC + `","Level":"Crate","DrugsID":["D1"]}`, &Aggregate{}, CodeInvalid, "Level"},
+		{"aggregate drugs on pallet", `{"ContainerID":"` + testSSCC + `","Level":"Pallet","DrugsID":["D1"]}`, &Aggregate{}, CodeInvalid, "DrugsID"},
+		{"aggregate nothing packed", `{"ContainerID":"` + testSSCC + `","Level":"Case"}`, &Aggregate{}, CodeRequired, "DrugsID"},
+		{"aggregate duplicate drug", `{"ContainerID":"` + testSSCC + `","Level":"Case","DrugsID":["D1","D1"]}`, &Aggregate{}, CodeDuplicate, "DrugsID[1]"},
+		{"aggregate into itself", `{"ContainerID":"` + testSSCC + `","Level":"Pallet","ContainersID":["` + testSSCC + `"]}`, &Aggregate{}, CodeInvalid, "ContainersID[0]"},
+		{"create batch", `{"Amount":2,"DrugName":"Paracetamol","ProductionDate":"2025-01-01T00:00:00Z","ExpiryDate":"2027-01-01T00:00:00Z"}`, &CreateBatch{}, "", ""},
+		{"create batch zero amount", `{"Amount":0,"DrugName":"Paracetamol","ProductionDate":"2025-01-01T00:00:00Z","ExpiryDate":"2027-01-01T00:00:00Z"}`, &CreateBatch{}, CodeOutOfRange, "Amount"},
+		{"create batch amount too large", `{"Amount":100000000,"DrugName":"Paracetamol","ProductionDate":"2025-01-01T00:00:00Z","ExpiryDate":"2027-01-01T00:00:00Z"}`, &CreateBatch{}, CodeOutOfRange, "Amount"},
+		{"create batch expiry first", `{"Amount":1,"DrugName":"Paracetamol","ProductionDate":"2027-01-01T00:00:00Z","ExpiryDate":"2025-01-01T00:00:00Z"}`, &CreateBatch{}, CodeInvalidOrder, "ExpiryDate"},
+		{"create batch serial count", `{"Amount":2,"DrugName":"Paracetamol","ProductionDate":"2025-01-01T00:00:00Z","ExpiryDate":"2027-01-01T00:00:00Z","SerialNumbers":["S1"]}`, &CreateBatch{}, CodeMismatch, "SerialNumbers"},
+		{"create batch missing lot", `{"Amount":1,"ProductID":"P1","ProductionDate":"2025-01-01T00:00:00Z","ExpiryDate":"2027-01-01T00:00:00Z"}`, &CreateBatch{}, CodeRequired, "LotNumber"},
+		{"create batch cold chain", `{"Amount":1,"DrugName":"Insulin","ProductionDate":"2025-01-01T00:00:00Z","ExpiryDate":"2027-01-01T00:00:00Z","StorageConditions":{"Temperature":{"Min":2,"Max":8}}}`, &CreateBatch{}, "", ""},
+		{"create batch reversed temperature", `{"Amount":1,"DrugName":"Insulin","ProductionDate":"2025-01-01T00:00:00Z","ExpiryDate":"2027-01-01T00:00:00Z","StorageConditions":{"Temperature":{"Min":8,"Max":2}}}`, &CreateBatch{}, CodeInvalidOrder, "StorageConditions.Temperature"},
+		{"create batch humidity above 100", `{"Amount":1,"DrugName":"Insulin","ProductionDate":"2025-01-01T00:00:00Z","ExpiryDate":"2027-01-01T00:00:00Z","StorageConditions":{"Humidity":{"Min":20,"Max":120}}}`, &CreateBatch{}, CodeOutOfRange, "StorageConditions.Humidity"},
+		{"create return", `{"OriginalTransferID":"T1","ReasonCode":"Damaged","Disposition":"NonSaleable","DrugsID":["D1"]}`, &CreateReturn{}, "", ""},
+		{"create return invalid reason", `{"OriginalTransferID":"T1","ReasonCode":"Lost","Disposition":"NonSaleable","DrugsID":["D1"]}`, &CreateReturn{}, CodeInvalid, "ReasonCode"},
+		{"create transfer null drug", `{"DrugsID":[null]}`, &CreateTransfer{}, CodeRequired, "DrugsID[0]"},
+		{"create transfer empty", `{}`, &CreateTransfer{}, CodeRequired, "DrugsID"},
+		{"decommission", `{"Kind":"Destroyed","ReasonCode":"Expired","WitnessID":"Org2","DrugsID":["D1"],"CertificateHash":"` + strings.Repeat("ab", 32) + `"}`, &DecommissionDrug{}, "", ""},
+		{"decommission missing witness", `{"Kind":"Sample","ReasonCode":"QualityTesting","DrugsID":["D1"]}`, &DecommissionDrug{}, CodeRequired, "WitnessID"},
+		{"decommission short hash", `{"Kind":"Destroyed","ReasonCode":"Expired","WitnessID":"Org2","DrugsID":["D1"],"CertificateHash":"abcd"}`, &DecommissionDrug{}, CodeInvalid, "CertificateHash"},
+		{"decommission hash on theft", `{"Kind":"Stolen","ReasonCode":"Theft","WitnessID":"Org2","DrugsID":["D1"],"CertificateHash":"` + strings.Repeat("ab", 32) + `"}`, &DecommissionDrug{}, CodeInvalid, "CertificateHash"},
+		{"dispense empty drug", `{"DrugsID":[""]}`, &DispenseDrug{}, CodeRequired, "DrugsID[0]"},
+		{"partial accept", `{"transferID":"T1","ReceivedDrugsID":["D1"],"RefusedDrugs":[{"DrugID":"D2","ReasonCode":"Damaged"}]}`, &PartialAcceptTransfer{}, "", ""},
+		{"partial accept missing transfer", `{"ReceivedDrugsID":["D1"]}`, &PartialAcceptTransfer{}, CodeRequired, "transferID"},
+		{"partial accept received and refused", `{"transferID":"T1","ReceivedDrugsID":["D1"],"RefusedDrugs":[{"DrugID":"D1","ReasonCode":"Damaged"}]}`, &PartialAcceptTransfer{}, CodeDuplicate, "RefusedDrugs[0].DrugID"},
+		{"partial accept invalid reason", `{"transferID":"T1","RefusedDrugs":[{"DrugID":"D1","ReasonCode":"Late"}]}`, &PartialAcceptTransfer{}, CodeInvalid, "RefusedDrugs[0].ReasonCode"},
+		{"place hold missing reason", `{"Scope":"Batch","TargetID":"B1"}`, &PlaceHold{}, CodeRequired, "Reason"},
+		{"query drugs reversed range", `{"ExpiryFrom":"2027-01-01T00:00:00Z","ExpiryTo":"2026-01-01T00:00:00Z"}`, &QueryDrugs{}, CodeInvalidOrder, "ExpiryTo"},
+		{"query transfers invalid status", `{"Status":"Lost"}`, &QueryTransfers{}, CodeInvalid, "Status"},
+		{"condition reading without value", `{"TransferID":"T1","SensorID":"S1"}`, &RecordConditionReading{}, CodeRequired, "Temperature"},
+		{"scan", `{"DrugID":"D1","Latitude":-6.2,"Longitude":106.8}`, &RecordScan{}, "", ""},
+		{"scan latitude only", `{"DrugID":"D1","Latitude":-6.2}`, &RecordScan{}, CodeRequired, "Latitude"},
+		{"scan longitude out of range", `{"DrugID":"D1","Latitude":0,"Longitude":181}`, &RecordScan{}, CodeOutOfRange, "Longitude"},
+		{"register organization blank name", `{"ID":"Org1","Name":" ","Type":"Pharmacy"}`, &RegisterOrganization{}, CodeRequired, "Name"},
+		{"register organization invalid type", `{"ID":"Org1","Name":"Apotek","Type":"Clinic"}`, &RegisterOrganization{}, CodeInvalid, "Type"},
+		{"register product invalid GTIN", `{"GTIN":"09506000134353","GenericName":"Paracetamol","Strength":"500 mg","DosageForm":"Tablet","MarketingAuthorizationNumber":"MA1","PackSize":10}`, &RegisterProduct{}, CodeInvalid, "GTIN"},
+		{"register product pack size", `{"GTIN":"09506000134352","GenericName":"Paracetamol","Strength":"500 mg","DosageForm":"Tablet","MarketingAuthorizationNumber":"MA1","PackSize":0}`, &RegisterProduct{}, CodeOutOfRange, "PackSize"},
+		{"transfer rules", `[{"SenderType":"Manufacturer","ReceiverType":"Distributor"}]`, &SetTransferRules{}, "", ""},
+		{"transfer rules empty", `[]`, &SetTransferRules{}, CodeRequired, ""},
+		{"transfer rules null", `[null]`, &SetTransferRules{}, CodeRequired, "[0]"},
+		{"transfer rules duplicate", `[{"SenderType":"Manufacturer","ReceiverType":"Distributor"},{"SenderType":"Manufacturer","ReceiverType":"Distributor"}]`, &SetTransferRules{}, CodeDuplicate, "[1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Decode(tt.req, tt.v)
+			assertFirstError(t, err, tt.code, tt.field)
+		})
+	}
+}
+
+func TestValidationErrorCollectsEveryField(t *testing.T) {
+	err := Decode(`{"Kind":"Lost","ReasonCode":"Lost","DrugsID":["D1","D1"]}`, &DecommissionDrug{})
+
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Decode() error = %v, want *ValidationError", err)
+	}
+
+	var fields []string
+	for _, fieldError := range validation.Errors {
+		fields = append(fields, fieldError.Field)
+	}
+	want := []string{"Kind", "ReasonCode", "WitnessID", "DrugsID[1]"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestValidationErrorJSON(t *testing.T) {
+	err := Decode(`{"DrugsID":["D1","D1"]}`, &DispenseDrug{})
+	if err == nil {
+		t.Fatal("Decode() error = nil, want a validation error")
+	}
+
+	want := `{"Errors":[{"Code":"DUPLICATE","Field":"DrugsID[1]","Message":"D1 is listed more than once"}]}`
+	if err.Error() != want {
+		t.Errorf("Error() = %s, want %s", err.Error(), want)
+	}
+
+	var decoded ValidationError
+	if err := json.Unmarshal([]byte(err.Error()), &decoded); err != nil {
+		t.Fatalf("Error() is not valid JSON: %v", err)
+	}
+	if len(decoded.Errors) != 1 || decoded.Errors[0].Code != CodeDuplicate {
+		t.Errorf("decoded errors = %+v, want one %s error", decoded.Errors, CodeDuplicate)
+	}
+}
+
+func assertFirstError(t *testing.T, err error, code string, field string) {
+	t.Helper()
+
+	if code == "" {
+		if err != nil {
+			t.Fatalf("Decode() error = %v, want nil", err)
+		}
+		return
+	}
+
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Decode() error = %v, want *ValidationError", err)
+	}
+	if len(validation.Errors) == 0 {
+		t.Fatal("Decode() returned a ValidationError without field errors")
+	}
+	if got := validation.Errors[0]; got.Code != code || got.Field != field {
+		t.Errorf("first error = %s %q (%s), want %s %q", got.Code, got.Field, got.Message, code, field)
+	}
+}
+
+func TestCreateBatchValidateProduct(t *testing.T) {
+	product := &model.Product{ID: "P1", GTIN: "09506000134352", GenericName: "Paracetamol"}
+
+	tests := []struct {
+		name  string
+		batch CreateBatch
+		code  string
+		field string
+	}{
+		{"product values omitted", CreateBatch{}, "", ""},
+		{"matching values", CreateBatch{DrugName: "Paracetamol", GTIN: "9506000134352"}, "", ""},
+		{"different drug name", CreateBatch{DrugName: "Ibuprofen"}, CodeMismatch, "DrugName"},
+		{"different GTIN", CreateBatch{GTIN: "96385074"}, CodeMismatch, "GTIN"},
+		{"invalid GTIN", CreateBatch{GTIN: "09506000134353"}, CodeInvalid, "GTIN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.batch.ValidateProduct(product)
+			assertFirstError(t, err, tt.code, tt.field)
+		})
+	}
+}
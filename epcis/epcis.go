@@ -0,0 +1,261 @@
+// Package epcis builds and validates GS1 EPCIS 2.0 JSON-LD documents made of
+// ObjectEvents, the subset of EPCIS that MedTrace uses to publish commissioning,
+// shipping and receiving of drugs to trading partners and regulators.
+package epcis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	Context       = "https://ref.gs1.org/standards/epcis/2.0.0/epcis-context.jsonld"
+	SchemaVersion = "2.0"
+
+	TypeDocument    = "EPCISDocument"
+	TypeObjectEvent = "ObjectEvent"
+)
+
+const (
+	ActionAdd     = "ADD"
+	ActionObserve = "OBSERVE"
+	ActionDelete  = "DELETE"
+)
+
+// CBV 2.0 business steps used by MedTrace.
+const (
+	BizStepCommissioning   = "commissioning"
+	BizStepDecommissioning = "decommissioning"
+	BizStepDestroying      = "destroying"
+	BizStepDispensing      = "dispensing"
+	BizStepHolding         = "holding"
+	BizStepInspecting      = "inspecting"
+	BizStepReceiving       = "receiving"
+	BizStepShipping        = "shipping"
+	BizStepVoidShipping    = "void_shipping"
+)
+
+// CBV 2.0 dispositions used by MedTrace.
+const (
+	DispositionActive           = "active"
+	DispositionDamaged          = "damaged"
+	DispositionDestroyed        = "destroyed"
+	DispositionDispensed        = "dispensed"
+	DispositionExpired          = "expired"
+	DispositionInProgress       = "in_progress"
+	DispositionInTransit        = "in_transit"
+	DispositionNonSellableOther = "non_sellable_other"
+	DispositionRecalled         = "recalled"
+	DispositionReturned         = "returned"
+	DispositionStolen           = "stolen"
+)
+
+// CBV 2.0 source/destination and business transaction types used by MedTrace.
+const (
+	PartyOwning          = "owning_party"
+	BizTransactionDesadv = "desadv"
+)
+
+// CBV master data attributes for instance/lot master data (ILMD).
+const (
+	ILMDLotNumber          = "cbvmda:lotNumber"
+	ILMDItemExpirationDate = "cbvmda:itemExpirationDate"
+)
+
+var (
+	bizSteps = map[string]bool{
+		BizStepCommissioning: true, BizStepDecommissioning: true, BizStepDestroying: true,
+		BizStepDispensing: true, BizStepHolding: true, BizStepInspecting: true,
+		BizStepReceiving: true, BizStepShipping: true, BizStepVoidShipping: true,
+	}
+	dispositions = map[string]bool{
+		DispositionActive: true, DispositionDamaged: true, DispositionDestroyed: true,
+		DispositionDispensed: true, DispositionExpired: true, DispositionInProgress: true,
+		DispositionInTransit: true, DispositionNonSellableOther: true, DispositionRecalled: true,
+		DispositionReturned: true, DispositionStolen: true,
+	}
+	timeZoneOffset = regexp.MustCompile(`^[+-]([01][0-9]|2[0-3]):[0-5][0-9]$`)
+)
+
+type Document struct {
+	Context       []string  `json:"@context"`
+	CreationDate  time.Time `json:"creationDate"`
+	EPCISBody     Body      `json:"epcisBody"`
+	SchemaVersion string    `json:"schemaVersion"`
+	Type          string    `json:"type"`
+}
+
+type Body struct {
+	EventList []*ObjectEvent `json:"eventList"`
+}
+
+type ObjectEvent struct {
+	Action              string                 `json:"action"`
+	BizLocation         *Location              `json:"bizLocation,omitempty"`
+	BizStep             string                 `json:"bizStep,omitempty"`
+	BizTransactionList  []*BizTransaction      `json:"bizTransactionList,omitempty"`
+	DestinationList     []*Destination         `json:"destinationList,omitempty"`
+	Disposition         string                 `json:"disposition,omitempty"`
+	EPCList             []string               `json:"epcList"`
+	EventTime           time.Time              `json:"eventTime"`
+	EventTimeZoneOffset string                 `json:"eventTimeZoneOffset"`
+	ILMD                map[string]interface{} `json:"ilmd,omitempty"`
+	ReadPoint           *Location              `json:"readPoint,omitempty"`
+	SourceList          []*Source              `json:"sourceList,omitempty"`
+	Type                string                 `json:"type"`
+}
+
+type Location struct {
+	ID string `json:"id"`
+}
+
+type BizTransaction struct {
+	BizTransaction string `json:"bizTransaction"`
+	Type           string `json:"type,omitempty"`
+}
+
+type Source struct {
+	Source string `json:"source"`
+	Type   string `json:"type"`
+}
+
+type Destination struct {
+	Destination string `json:"destination"`
+	Type        string `json:"type"`
+}
+
+func NewDocument(creationDate time.Time) *Document {
+	return &Document{
+		Context:       []string{Context},
+		CreationDate:  creationDate.UTC(),
+		EPCISBody:     Body{EventList: make([]*ObjectEvent, 0)},
+		SchemaVersion: SchemaVersion,
+		Type:          TypeDocument,
+	}
+}
+
+// NewObjectEvent creates an ObjectEvent whose time zone offset is taken from eventTime.
+func NewObjectEvent(eventTime time.Time, action string, bizStep string, disposition string, epcs ...string) *ObjectEvent {
+	return &ObjectEvent{
+		Action:              action,
+		BizStep:             bizStep,
+		Disposition:         disposition,
+		EPCList:             epcs,
+		EventTime:           eventTime,
+		EventTimeZoneOffset: eventTime.Format("-07:00"),
+		Type:                TypeObjectEvent,
+	}
+}
+
+func (e *ObjectEvent) At(readPoint string, bizLocation string) *ObjectEvent {
+	e.ReadPoint = &Location{ID: readPoint}
+	e.BizLocation = &Location{ID: bizLocation}
+	return e
+}
+
+func (e *ObjectEvent) WithBizTransaction(transactionType string, id string) *ObjectEvent {
+	e.BizTransactionList = append(e.BizTransactionList, &BizTransaction{BizTransaction: id, Type: transactionType})
+	return e
+}
+
+func (e *ObjectEvent) WithSource(partyType string, id string) *ObjectEvent {
+	e.SourceList = append(e.SourceList, &Source{Source: id, Type: partyType})
+	return e
+}
+
+func (e *ObjectEvent) WithDestination(partyType string, id string) *ObjectEvent {
+	e.DestinationList = append(e.DestinationList, &Destination{Destination: id, Type: partyType})
+	return e
+}
+
+func (e *ObjectEvent) WithILMD(key string, value interface{}) *ObjectEvent {
+	if e.ILMD == nil {
+		e.ILMD = make(map[string]interface{})
+	}
+	e.ILMD[key] = value
+	return e
+}
+
+// AddEvents appends events and keeps the event list ordered by event time.
+func (d *Document) AddEvents(events ...*ObjectEvent) {
+	d.EPCISBody.EventList = append(d.EPCISBody.EventList, events...)
+	sort.SliceStable(d.EPCISBody.EventList, func(i, j int) bool {
+		return d.EPCISBody.EventList[i].EventTime.Before(d.EPCISBody.EventList[j].EventTime)
+	})
+}
+
+// Validate checks the document against the EPCIS 2.0 rules that apply to the
+// events MedTrace produces and returns the first violation found.
+func (d *Document) Validate() error {
+	if d.Type != TypeDocument {
+		return fmt.Errorf("document type must be %s", TypeDocument)
+	}
+	if d.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("schema version must be %s", SchemaVersion)
+	}
+	if len(d.Context) == 0 || d.Context[0] != Context {
+		return fmt.Errorf("document @context must start with %s", Context)
+	}
+	if d.CreationDate.IsZero() {
+		return fmt.Errorf("document creation date must be set")
+	}
+
+	for i, e := range d.EPCISBody.EventList {
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("event %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (e *ObjectEvent) Validate() error {
+	if e.Type != TypeObjectEvent {
+		return fmt.Errorf("event type must be %s", TypeObjectEvent)
+	}
+	switch e.Action {
+	case ActionAdd, ActionObserve, ActionDelete:
+	default:
+		return fmt.Errorf("invalid action %q", e.Action)
+	}
+	if e.EventTime.IsZero() {
+		return fmt.Errorf("event time must be set")
+	}
+	if !timeZoneOffset.MatchString(e.EventTimeZoneOffset) {
+		return fmt.Errorf("invalid event time zone offset %q", e.EventTimeZoneOffset)
+	}
+	if len(e.EPCList) == 0 {
+		return fmt.Errorf("epc list must not be empty")
+	}
+	for _, epc := range e.EPCList {
+		if epc == "" {
+			return fmt.Errorf("epc list must not contain empty identifiers")
+		}
+	}
+	if e.BizStep != "" && !bizSteps[e.BizStep] {
+		return fmt.Errorf("unknown business step %q", e.BizStep)
+	}
+	if e.Disposition != "" && !dispositions[e.Disposition] {
+		return fmt.Errorf("unknown disposition %q", e.Disposition)
+	}
+	if e.ILMD != nil && e.Action != ActionAdd {
+		return fmt.Errorf("ilmd is only allowed on ADD events")
+	}
+	for _, location := range []*Location{e.ReadPoint, e.BizLocation} {
+		if location != nil && location.ID == "" {
+			return fmt.Errorf("locations must have an id")
+		}
+	}
+	for _, source := range e.SourceList {
+		if source.Source == "" || source.Type == "" {
+			return fmt.Errorf("sources must have a type and an id")
+		}
+	}
+	for _, destination := range e.DestinationList {
+		if destination.Destination == "" || destination.Type == "" {
+			return fmt.Errorf("destinations must have a type and an id")
+		}
+	}
+	return nil
+}
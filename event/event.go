@@ -0,0 +1,227 @@
+// Package event defines the chaincode event emitted by MedTrace transactions.
+//
+// Fabric keeps a single chaincode event per transaction, so every state-changing
+// transaction emits one Envelope under the name Name. The envelope carries one or
+// more typed sub-events whose payload schema is selected by Event.Type.
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// Name is the chaincode event name listeners should subscribe to.
+	Name = "MedTrace"
+	// Version is the current envelope schema version.
+	Version = 1
+)
+
+const (
+	TypeBatchCreated              = "BatchCreated"
+	TypeBatchUpdated              = "BatchUpdated"
+	TypeBatchRecalled             = "BatchRecalled"
+	TypeTransferCreated           = "TransferCreated"
+	TypeTransferAccepted          = "TransferAccepted"
+	TypeTransferPartiallyAccepted = "TransferPartiallyAccepted"
+	TypeTransferRejected          = "TransferRejected"
+	TypeTransferCancelled         = "TransferCancelled"
+	TypeTransferRulesUpdated      = "TransferRulesUpdated"
+	TypeTimestampToleranceUpdated = "TimestampToleranceUpdated"
+	TypeOrganizationRegistered    = "OrganizationRegistered"
+	TypeOrganizationUpdated       = "OrganizationUpdated"
+	TypeOrganizationSuspended     = "OrganizationSuspended"
+	TypeDrugScanned               = "DrugScanned"
+	TypeDrugFlagged               = "DrugFlagged"
+	TypeDrugAlertsCleared         = "DrugAlertsCleared"
+	TypeContainerAggregated       = "ContainerAggregated"
+	TypeContainerDisaggregated    = "ContainerDisaggregated"
+	TypeConditionReadingRecorded  = "ConditionReadingRecorded"
+	TypeConditionExcursion        = "ConditionExcursion"
+	TypeProductRegistered         = "ProductRegistered"
+	TypeProductUpdated            = "ProductUpdated"
+	TypeHoldPlaced                = "HoldPlaced"
+	TypeHoldReleased              = "HoldReleased"
+	TypeDrugDispensed             = "DrugDispensed"
+	TypeDecommissionRequested     = "DecommissionRequested"
+	TypeDrugDecommissioned        = "DrugDecommissioned"
+	TypeDecommissionRejected      = "DecommissionRejected"
+	TypeDecommissionCancelled     = "DecommissionCancelled"
+)
+
+type Envelope struct {
+	Events  []*Event `json:"Events"`  // Sub-events raised by the transaction, in order
+	TxID    string   `json:"TxID"`    // ID of the transaction that raised the events
+	Version int      `json:"Version"` // Envelope schema version
+}
+
+type Event struct {
+	Payload json.RawMessage `json:"Payload"` // Type-specific payload
+	Type    string          `json:"Type"`    // Sub-event type, selects the payload schema
+}
+
+// BatchCreated describes the commissioned drugs by their ID range rather than
+// listing them, so large batches do not produce oversized events. Listeners can
+// page through the drugs with GetDrugByBatchWithPagination.
+type BatchCreated struct {
+	Amount         int    `json:"Amount"`              // Number of drugs commissioned
+	BatchID        string `json:"BatchID"`             // Reference to Batch.ID
+	DrugIDPrefix   string `json:"DrugIDPrefix"`        // Prefix shared by the IDs of the commissioned drugs
+	DrugName       string `json:"DrugName"`            // Drug name
+	FirstDrugID    string `json:"FirstDrugID"`         // ID of the first commissioned drug
+	LastDrugID     string `json:"LastDrugID"`          // ID of the last commissioned drug
+	ManufacturerID string `json:"ManufacturerID"`      // Manufacturer organization ID
+	ProductID      string `json:"ProductID,omitempty"` // Reference to Product.ID, if the batch has one
+}
+
+type BatchUpdated struct {
+	BatchID        string `json:"BatchID"`        // Reference to Batch.ID
+	ManufacturerID string `json:"ManufacturerID"` // Manufacturer organization ID
+}
+
+type BatchRecalled struct {
+	BatchID        string `json:"BatchID"`        // Reference to Batch.ID
+	RecallReason   string `json:"RecallReason"`   // Reason given for the recall
+	RecallSeverity string `json:"RecallSeverity"` // Recall severity
+	RecalledBy     string `json:"RecalledBy"`     // ID of the organization that ordered the recall
+	RecalledUnits  int    `json:"RecalledUnits"`  // Number of drugs flagged as recalled
+}
+
+type TransferCreated struct {
+	ContainersID []string `json:"ContainersID,omitempty"` // SSCCs of the transferred top-level containers
+	DrugsID      []string `json:"DrugsID"`                // IDs of the transferred drugs, including packed ones
+	IsReturn     bool     `json:"isReturn,omitempty"`     // Indicates if the transfer returns stock upstream
+	ReceiverID   string   `json:"ReceiverID"`             // Receiver ID
+	SenderID     string   `json:"SenderID"`               // Sender ID
+	TransferID   string   `json:"TransferID"`             // Reference to Transfer.ID
+}
+
+// TransferProcessed is the payload of the accepted, partially accepted, rejected
+// and cancelled transfer events.
+type TransferProcessed struct {
+	ReceivedDrugsID []string `json:"ReceivedDrugsID"` // IDs of drugs whose ownership moved to the receiver
+	ReceiverID      string   `json:"ReceiverID"`      // Receiver ID
+	ReturnedDrugsID []string `json:"ReturnedDrugsID"` // IDs of drugs that stayed with the sender
+	SenderID        string   `json:"SenderID"`        // Sender ID
+	Status          string   `json:"Status"`          // Resulting transfer status
+	TransferID      string   `json:"TransferID"`      // Reference to Transfer.ID
+}
+
+type TransferRulesUpdated struct {
+	RuleCount int `json:"RuleCount"` // Number of transfer rules now in force
+}
+
+type TimestampToleranceUpdated struct {
+	ToleranceSeconds int `json:"ToleranceSeconds"` // Allowed drift of declared dates from ledger time
+}
+
+type OrganizationChanged struct {
+	OrganizationID string `json:"OrganizationID"` // Reference to Organization.ID
+	Status         string `json:"Status"`         // Organization status after the change
+	Type           string `json:"Type"`           // Organization type after the change
+}
+
+type DrugScanned struct {
+	Alerts    []string `json:"Alerts"`    // Alert codes raised by the scan
+	DrugID    string   `json:"DrugID"`    // Reference to Drug.ID
+	ScanID    string   `json:"ScanID"`    // Reference to Scan.ID
+	ScannerID string   `json:"ScannerID"` // ID of the scanning organization
+}
+
+// DrugAlert is the payload of the flagged and alerts cleared drug events.
+type DrugAlert struct {
+	Alerts  []string `json:"Alerts"`  // Alert codes raised against the drug, or cleared from it
+	DrugID  string   `json:"DrugID"`  // Reference to Drug.ID
+	OwnerID string   `json:"OwnerID"` // Current owner
+}
+
+// ContainerChanged is the payload of the aggregated and disaggregated container
+// events. It lists only the direct children packed or unpacked.
+type ContainerChanged struct {
+	ContainerID  string   `json:"ContainerID"`  // SSCC of the container
+	ContainersID []string `json:"ContainersID"` // SSCCs of the child containers
+	DrugsID      []string `json:"DrugsID"`      // IDs of the child drugs
+	Level        string   `json:"Level"`        // Packaging level
+	OwnerID      string   `json:"OwnerID"`      // Container owner
+}
+
+// ConditionReading is the payload of the condition reading recorded and
+// condition excursion events.
+type ConditionReading struct {
+	ExcursionBatchesID []string `json:"ExcursionBatchesID"` // Batches whose storage ranges the reading breached
+	ReadingID          string   `json:"ReadingID"`          // Reference to ConditionReading.ID
+	RecordedBy         string   `json:"RecordedBy"`         // ID of the recording organization
+	TransferID         string   `json:"TransferID"`         // Reference to Transfer.ID
+}
+
+// ProductChanged is the payload of the registered and updated product events.
+type ProductChanged struct {
+	GTIN      string `json:"GTIN"`      // GS1 GTIN of the trade item
+	OwnerID   string `json:"OwnerID"`   // ID of the brand owner organization
+	ProductID string `json:"ProductID"` // Reference to Product.ID
+}
+
+// HoldChanged is the payload of the placed and released hold events.
+type HoldChanged struct {
+	HoldID   string `json:"HoldID"`   // Reference to Hold.ID
+	IssuerID string `json:"IssuerID"` // ID of the organization that placed the hold
+	Reason   string `json:"Reason"`   // Reason given for the hold
+	Scope    string `json:"Scope"`    // Hold scope
+	TargetID string `json:"TargetID"` // Drug, batch or organization ID the hold applies to
+}
+
+// DrugDispensed omits the patient reference so it is not broadcast to listeners.
+type DrugDispensed struct {
+	DispenseID     string   `json:"DispenseID"`     // Reference to Dispense.ID
+	DrugsID        []string `json:"DrugsID"`        // IDs of the dispensed drugs
+	PharmacyID     string   `json:"PharmacyID"`     // ID of the dispensing pharmacy
+	PrescriptionID string   `json:"PrescriptionID"` // Prescription ID, empty if not recorded
+}
+
+// DecommissionChanged is the payload of the requested, drug decommissioned,
+// rejected and cancelled decommission events.
+type DecommissionChanged struct {
+	DecommissionID string   `json:"DecommissionID"` // Reference to Decommission.ID
+	DrugsID        []string `json:"DrugsID"`        // IDs of the drugs in the decommission
+	Kind           string   `json:"Kind"`           // Decommission kind
+	OwnerID        string   `json:"OwnerID"`        // ID of the organization that held the drugs
+	ReasonCode     string   `json:"ReasonCode"`     // Decommission reason code
+	Status         string   `json:"Status"`         // Resulting decommission status
+	WitnessID      string   `json:"WitnessID"`      // ID of the witnessing organization
+}
+
+// New wraps a typed payload into a sub-event.
+func New(eventType string, payload interface{}) (*Event, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	return &Event{
+		Payload: payloadJSON,
+		Type:    eventType,
+	}, nil
+}
+
+// Decode unmarshals the sub-event payload into v, which should be the payload
+// struct matching e.Type.
+func (e *Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s payload: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Parse decodes an envelope received from a chaincode event and rejects
+// envelope versions newer than this package understands.
+func Parse(payload []byte) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event envelope: %w", err)
+	}
+	if envelope.Version < 1 || envelope.Version > Version {
+		return nil, fmt.Errorf("unsupported event envelope version %d", envelope.Version)
+	}
+
+	return &envelope, nil
+}
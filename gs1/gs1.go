@@ -0,0 +1,359 @@
+// Package gs1 validates and encodes the GS1 identifiers printed on drug packs:
+// GTINs, serial numbers and lot numbers, the DataMatrix element strings that
+// combine them into an SGTIN, and the SSCCs of the cases and pallets they ship in.
+package gs1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AISSCC       = "00"
+	AIGTIN       = "01"
+	AIExpiryDate = "17"
+	AILotNumber  = "10"
+	AISerial     = "21"
+
+	// GroupSeparator terminates variable-length fields in raw DataMatrix data (FNC1).
+	GroupSeparator = "\x1d"
+
+	maxSerialLength = 20
+	maxLotLength    = 20
+	gtinLength      = 14
+	ssccLength      = 18
+	expiryLayout    = "060102"
+)
+
+// symbologyIdentifiers are prepended by some scanners to GS1 DataMatrix and GS1-128 data.
+var symbologyIdentifiers = []string{"]d2", "]C1", "]Q3"}
+
+// SGTIN is a serialized GTIN together with the lot and expiry printed next to it.
+type SGTIN struct {
+	ExpiryDate *time.Time // Expiry date (AI 17), nil when absent
+	GTIN       string     // 14-digit GTIN (AI 01)
+	LotNumber  string     // Lot or batch number (AI 10), empty when absent
+	Serial     string     // Serial number (AI 21)
+}
+
+// NormalizeGTIN left-pads GTIN-8, GTIN-12 and GTIN-13 to the 14-digit form and
+// verifies the check digit.
+func NormalizeGTIN(gtin string) (string, error) {
+	switch len(gtin) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("GTIN %q must have 8, 12, 13 or 14 digits", gtin)
+	}
+	for _, r := range gtin {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("GTIN %q must contain digits only", gtin)
+		}
+	}
+
+	gtin = strings.Repeat("0", gtinLength-len(gtin)) + gtin
+	if want := CheckDigit(gtin[:gtinLength-1]); int(gtin[gtinLength-1]-'0') != want {
+		return "", fmt.Errorf("GTIN %s has an invalid check digit, expected %d", gtin, want)
+	}
+
+	return gtin, nil
+}
+
+// ValidateSSCC checks an 18-digit Serial Shipping Container Code (AI 00), the
+// identifier printed on cases and pallets, including its check digit.
+func ValidateSSCC(sscc string) error {
+	if len(sscc) != ssccLength {
+		return fmt.Errorf("SSCC %q must have %d digits", sscc, ssccLength)
+	}
+	for _, r := range sscc {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("SSCC %q must contain digits only", sscc)
+		}
+	}
+	if want := CheckDigit(sscc[:ssccLength-1]); int(sscc[ssccLength-1]-'0') != want {
+		return fmt.Errorf("SSCC %s has an invalid check digit, expected %d", sscc, want)
+	}
+
+	return nil
+}
+
+// CheckDigit computes the GS1 mod-10 check digit for a string of digits that
+// excludes the check digit itself.
+func CheckDigit(digits string) int {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		// Weights alternate 3, 1, 3, ... starting from the rightmost digit.
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// ValidateSerial checks a serial number against the length and GS1 AI encodable
+// character set 82 rules for AI 21.
+func ValidateSerial(serial string) error {
+	return validateAlphanumeric("serial number", serial, maxSerialLength)
+}
+
+// ValidateLotNumber checks a lot number against the rules for AI 10.
+func ValidateLotNumber(lot string) error {
+	return validateAlphanumeric("lot number", lot, maxLotLength)
+}
+
+func validateAlphanumeric(field string, value string, maxLength int) error {
+	if value == "" {
+		return fmt.Errorf("%s must not be empty", field)
+	}
+	if len(value) > maxLength {
+		return fmt.Errorf("%s %q exceeds %d characters", field, value, maxLength)
+	}
+	for _, r := range value {
+		if !isCSet82(r) {
+			return fmt.Errorf("%s %q contains character %q outside the GS1 character set", field, value, r)
+		}
+	}
+	return nil
+}
+
+func isCSet82(r rune) bool {
+	switch {
+	case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune(`!"%&'()*+,-./:;<=>?_`, r)
+}
+
+// ElementString renders the SGTIN in the human readable form printed under the
+// DataMatrix, e.g. (01)09506000134352(17)271231(10)LOT1(21)SER1.
+func (s *SGTIN) ElementString() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "(%s)%s", AIGTIN, s.GTIN)
+	if s.ExpiryDate != nil {
+		fmt.Fprintf(&b, "(%s)%s", AIExpiryDate, s.ExpiryDate.Format(expiryLayout))
+	}
+	if s.LotNumber != "" {
+		fmt.Fprintf(&b, "(%s)%s", AILotNumber, s.LotNumber)
+	}
+	fmt.Fprintf(&b, "(%s)%s", AISerial, s.Serial)
+	return b.String()
+}
+
+// DigitalLink renders the SGTIN as a GS1 Digital Link URI, which EPCIS 2.0 accepts
+// as an EPC identifier without knowing the GS1 company prefix length.
+func (s *SGTIN) DigitalLink() string {
+	return DigitalLink(s.GTIN, s.Serial)
+}
+
+func DigitalLink(gtin string, serial string) string {
+	return fmt.Sprintf("https://id.gs1.org/%s/%s/%s/%s", AIGTIN, gtin, AISerial, escapeDigitalLink(serial))
+}
+
+func escapeDigitalLink(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || strings.ContainsRune("-._", r) {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", r)
+		}
+	}
+	return b.String()
+}
+
+// Parse decodes a scanned code in either the human readable "(01)...(21)..." form
+// or the raw form where variable-length fields are terminated by GroupSeparator.
+// A GTIN and serial number are required; lot and expiry are optional.
+func Parse(code string) (*SGTIN, error) {
+	for _, prefix := range symbologyIdentifiers {
+		code = strings.TrimPrefix(code, prefix)
+	}
+
+	var fields map[string]string
+	var err error
+	if strings.HasPrefix(code, "(") {
+		fields, err = parseBracketed(code)
+	} else {
+		fields, err = parseRaw(code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gtin, ok := fields[AIGTIN]
+	if !ok {
+		return nil, fmt.Errorf("code %q has no GTIN (AI %s)", code, AIGTIN)
+	}
+	gtin, err = NormalizeGTIN(gtin)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, ok := fields[AISerial]
+	if !ok {
+		return nil, fmt.Errorf("code %q has no serial number (AI %s)", code, AISerial)
+	}
+	if err := ValidateSerial(serial); err != nil {
+		return nil, err
+	}
+
+	sgtin := &SGTIN{
+		GTIN:   gtin,
+		Serial: serial,
+	}
+
+	if lot, ok := fields[AILotNumber]; ok {
+		if err := ValidateLotNumber(lot); err != nil {
+			return nil, err
+		}
+		sgtin.LotNumber = lot
+	}
+
+	if expiry, ok := fields[AIExpiryDate]; ok {
+		expiryDate, err := parseExpiry(expiry)
+		if err != nil {
+			return nil, err
+		}
+		sgtin.ExpiryDate = &expiryDate
+	}
+
+	return sgtin, nil
+}
+
+func parseBracketed(code string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for code != "" {
+		if !strings.HasPrefix(code, "(") {
+			return nil, fmt.Errorf("expected an application identifier at %q", code)
+		}
+		end := strings.Index(code, ")")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated application identifier at %q", code)
+		}
+		ai := code[1:end]
+		code = code[end+1:]
+
+		next := strings.Index(code, "(")
+		// Serial and lot numbers may legally contain parentheses, so only treat
+		// "(" as the start of the next field when it is followed by a known AI.
+		for next >= 0 && !startsWithKnownAI(code[next:]) {
+			following := strings.Index(code[next+1:], "(")
+			if following < 0 {
+				next = -1
+				break
+			}
+			next += 1 + following
+		}
+
+		value := code
+		if next >= 0 {
+			value = code[:next]
+			code = code[next:]
+		} else {
+			code = ""
+		}
+
+		if err := addField(fields, ai, value); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+func startsWithKnownAI(code string) bool {
+	for _, ai := range []string{AIGTIN, AIExpiryDate, AILotNumber, AISerial} {
+		if strings.HasPrefix(code, "("+ai+")") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRaw(code string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for code != "" {
+		code = strings.TrimPrefix(code, GroupSeparator)
+		if code == "" {
+			break
+		}
+		if len(code) < 2 {
+			return nil, fmt.Errorf("truncated application identifier at %q", code)
+		}
+		ai := code[:2]
+		code = code[2:]
+
+		var value string
+		switch ai {
+		case AIGTIN, AIExpiryDate:
+			length := gtinLength
+			if ai == AIExpiryDate {
+				length = len(expiryLayout)
+			}
+			if len(code) < length {
+				return nil, fmt.Errorf("application identifier %s requires %d characters", ai, length)
+			}
+			value, code = code[:length], code[length:]
+		case AILotNumber, AISerial:
+			end := strings.Index(code, GroupSeparator)
+			if end < 0 {
+				end = len(code)
+			}
+			value, code = code[:end], code[end:]
+		default:
+			return nil, fmt.Errorf("unsupported application identifier %s", ai)
+		}
+
+		if err := addField(fields, ai, value); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+func addField(fields map[string]string, ai string, value string) error {
+	switch ai {
+	case AIGTIN, AIExpiryDate, AILotNumber, AISerial:
+	default:
+		return fmt.Errorf("unsupported application identifier %s", ai)
+	}
+	if _, ok := fields[ai]; ok {
+		return fmt.Errorf("application identifier %s appears more than once", ai)
+	}
+	fields[ai] = value
+	return nil
+}
+
+// parseExpiry decodes AI 17 dates. A day of 00 means the last day of the month.
+// Years are taken to be in the 2000s, which covers any pack on the market today.
+func parseExpiry(value string) (time.Time, error) {
+	if len(value) != len(expiryLayout) {
+		return time.Time{}, fmt.Errorf("expiry date %q must be in YYMMDD form", value)
+	}
+
+	var parts [3]int
+	for i := range parts {
+		n, err := strconv.Atoi(value[i*2 : i*2+2])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("expiry date %q must be in YYMMDD form", value)
+		}
+		parts[i] = n
+	}
+	year, month, day := 2000+parts[0], time.Month(parts[1]), parts[2]
+
+	if month < time.January || month > time.December {
+		return time.Time{}, fmt.Errorf("expiry date %q has an invalid month", value)
+	}
+	if day == 0 {
+		return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC), nil
+	}
+
+	expiry := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if expiry.Day() != day {
+		return time.Time{}, fmt.Errorf("expiry date %q has an invalid day", value)
+	}
+
+	return expiry, nil
+}
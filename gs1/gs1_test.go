@@ -0,0 +1,221 @@
+package gs1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   int
+	}{
+		{"0950600013435", 2},
+		{"10614141123456789", 7},
+		{"00614141123456789", 0},
+		{"9638507", 4},
+		{"000000000000", 0},
+	}
+
+	for _, tt := range tests {
+		if got := CheckDigit(tt.digits); got != tt.want {
+			t.Errorf("CheckDigit(%q) = %d, want %d", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		name    string
+		gtin    string
+		want    string
+		wantErr bool
+	}{
+		{"GTIN-14", "09506000134352", "09506000134352", false},
+		{"GTIN-13", "9506000134352", "09506000134352", false},
+		{"GTIN-12", "614141000036", "00614141000036", false},
+		{"GTIN-8", "96385074", "00000096385074", false},
+		{"bad check digit", "09506000134353", "", true},
+		{"letters", "0950600013435A", "", true},
+		{"too short", "1234567", "", true},
+		{"GTIN-11", "12345678901", "", true},
+		{"too long", "095060001343520", "", true},
+		{"empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeGTIN(tt.gtin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeGTIN(%q) error = %v, wantErr %v", tt.gtin, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeGTIN(%q) = %q, want %q", tt.gtin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSSCC(t *testing.T) {
+	tests := []struct {
+		name    string
+		sscc    string
+		wantErr bool
+	}{
+		{"valid", "106141411234567897", false},
+		{"valid with zero check digit", "006141411234567890", false},
+		{"bad check digit", "106141411234567890", true},
+		{"too short", "10614141123456789", true},
+		{"too long", "1061414112345678970", true},
+		{"letters", "10614141123456789A", true},
+		{"AI prefix included", "00106141411234567897", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSSCC(tt.sscc); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSSCC(%q) error = %v, wantErr %v", tt.sscc, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	expiry := time.Date(2027, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		code    string
+		want    *SGTIN
+		wantErr bool
+	}{
+		{
+			name: "bracketed",
+			code: "(01)09506000134352(17)271231(10)LOT1(21)SER1",
+			want: &SGTIN{GTIN: "09506000134352", ExpiryDate: &expiry, LotNumber: "LOT1", Serial: "SER1"},
+		},
+		{
+			name: "raw with group separators",
+			code: "010950600013435217271231" + "10LOT1" + GroupSeparator + "21SER1",
+			want: &SGTIN{GTIN: "09506000134352", ExpiryDate: &expiry, LotNumber: "LOT1", Serial: "SER1"},
+		},
+		{
+			name: "raw serial before lot",
+			code: "0109506000134352" + "21SER1" + GroupSeparator + "10LOT1",
+			want: &SGTIN{GTIN: "09506000134352", LotNumber: "LOT1", Serial: "SER1"},
+		},
+		{
+			name: "raw with leading FNC1",
+			code: GroupSeparator + "0109506000134352" + "21SER1",
+			want: &SGTIN{GTIN: "09506000134352", Serial: "SER1"},
+		},
+		{
+			name: "DataMatrix symbology identifier",
+			code: "]d2010950600013435221SER1",
+			want: &SGTIN{GTIN: "09506000134352", Serial: "SER1"},
+		},
+		{
+			name: "GS1-128 symbology identifier",
+			code: "]C1(01)09506000134352(21)SER1",
+			want: &SGTIN{GTIN: "09506000134352", Serial: "SER1"},
+		},
+		{
+			name: "QR symbology identifier",
+			code: "]Q3(01)09506000134352(21)SER1",
+			want: &SGTIN{GTIN: "09506000134352", Serial: "SER1"},
+		},
+		{
+			name: "parentheses inside serial",
+			code: "(01)09506000134352(21)AB(12)CD(10)LOT1",
+			want: &SGTIN{GTIN: "09506000134352", LotNumber: "LOT1", Serial: "AB(12)CD"},
+		},
+		{
+			name: "trailing parenthesis in serial",
+			code: "(01)09506000134352(10)LOT1(21)SER(1)",
+			want: &SGTIN{GTIN: "09506000134352", LotNumber: "LOT1", Serial: "SER(1)"},
+		},
+		{
+			name: "GTIN-13 is padded",
+			code: "(01)9506000134352(21)SER1",
+			want: &SGTIN{GTIN: "09506000134352", Serial: "SER1"},
+		},
+		{name: "missing serial", code: "(01)09506000134352(10)LOT1", wantErr: true},
+		{name: "missing GTIN", code: "(21)SER1(10)LOT1", wantErr: true},
+		{name: "bad GTIN check digit", code: "(01)09506000134353(21)SER1", wantErr: true},
+		{name: "duplicate AI", code: "(01)09506000134352(21)SER1(21)SER2", wantErr: true},
+		{name: "unsupported AI", code: "(99)X(01)09506000134352(21)SER1", wantErr: true},
+		{name: "unsupported raw AI", code: "0109506000134352" + "99X" + GroupSeparator + "21SER1", wantErr: true},
+		{name: "unterminated AI", code: "(01)09506000134352(21", wantErr: true},
+		{name: "truncated raw GTIN", code: "01095060001343", wantErr: true},
+		{name: "serial too long", code: "(01)09506000134352(21)ABCDEFGHIJKLMNOPQRSTU", wantErr: true},
+		{name: "serial outside character set", code: "(01)09506000134352(21)SER#1", wantErr: true},
+		{name: "invalid expiry", code: "(01)09506000134352(17)271301(21)SER1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.code, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.GTIN != tt.want.GTIN || got.LotNumber != tt.want.LotNumber || got.Serial != tt.want.Serial {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.code, got, tt.want)
+			}
+			if (got.ExpiryDate == nil) != (tt.want.ExpiryDate == nil) ||
+				(got.ExpiryDate != nil && !got.ExpiryDate.Equal(*tt.want.ExpiryDate)) {
+				t.Errorf("Parse(%q) expiry = %v, want %v", tt.code, got.ExpiryDate, tt.want.ExpiryDate)
+			}
+		})
+	}
+}
+
+func TestParseElementStringRoundTrip(t *testing.T) {
+	expiry := time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)
+	sgtin := &SGTIN{GTIN: "09506000134352", ExpiryDate: &expiry, LotNumber: "LOT1", Serial: "SER(1)"}
+
+	got, err := Parse(sgtin.ElementString())
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", sgtin.ElementString(), err)
+	}
+	if got.ElementString() != sgtin.ElementString() {
+		t.Errorf("round trip = %q, want %q", got.ElementString(), sgtin.ElementString())
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"plain date", "271231", time.Date(2027, time.December, 31, 0, 0, 0, 0, time.UTC), false},
+		{"day 00 is last day of month", "270400", time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC), false},
+		{"day 00 in December", "271200", time.Date(2027, time.December, 31, 0, 0, 0, 0, time.UTC), false},
+		{"day 00 in leap February", "280200", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC), false},
+		{"day 00 in February", "270200", time.Date(2027, time.February, 28, 0, 0, 0, 0, time.UTC), false},
+		{"29 February in leap year", "280229", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC), false},
+		{"29 February outside leap year", "270229", time.Time{}, true},
+		{"31 February", "270231", time.Time{}, true},
+		{"31 April", "270431", time.Time{}, true},
+		{"month 00", "270015", time.Time{}, true},
+		{"month 13", "271315", time.Time{}, true},
+		{"too short", "27123", time.Time{}, true},
+		{"not digits", "27AB31", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExpiry(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExpiry(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseExpiry(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,10 +5,21 @@ import (
 )
 
 type Batch struct {
-	DrugName            string    `json:"DrugName"`            // Drug name
-	ExpiryDate          time.Time `json:"ExpiryDate"`          // Expiry date for all drugs in the batch
-	ID                  string    `json:"ID"`                  // Unique batch ID
-	ManufacturerName    string    `json:"ManufacturerName"`    // Manufacturer name
-	ManufactureLocation string    `json:"ManufactureLocation"` // Manufacture timestamp
-	ProductionDate      time.Time `json:"ProductionDate"`      // Production date
+	DocType             string             `json:"docType"`                                          // Document type discriminator for rich queries
+	DrugName            string             `json:"DrugName"`                                         // Drug name, the product generic name when ProductID is set
+	ExpiryDate          time.Time          `json:"ExpiryDate"`                                       // Expiry date for all drugs in the batch
+	GTIN                string             `json:"GTIN"`                                             // GS1 GTIN shared by all drugs in the batch
+	ID                  string             `json:"ID"`                                               // Unique batch ID
+	IsRecalled          bool               `json:"isRecalled"`                                       // Indicates if the batch has been recalled
+	LotNumber           string             `json:"LotNumber"`                                        // GS1 lot number (AI 10) printed on the packs
+	ManufacturerID      string             `json:"ManufacturerID"`                                   // Manufacturer organization ID
+	ManufacturerName    string             `json:"ManufacturerName"`                                 // Manufacturer name
+	ManufactureLocation string             `json:"ManufactureLocation"`                              // Manufacture timestamp
+	ProductID           string             `json:"ProductID"`                                        // Reference to Product.ID, empty for batches created before the product registry
+	ProductionDate      time.Time          `json:"ProductionDate"`                                   // Production date
+	RecallDate          time.Time          `json:"RecallDate"`                                       // Recall date
+	RecallReason        string             `json:"RecallReason"`                                     // Reason given for the recall
+	RecallSeverity      string             `json:"RecallSeverity"`                                   // Recall severity (Class I, Class II, Class III)
+	RecalledBy          string             `json:"RecalledBy"`                                       // ID of the organization that ordered the recall
+	StorageConditions   *StorageConditions `json:"StorageConditions,omitempty" metadata:",optional"` // Required storage ranges, nil if uncontrolled
 }
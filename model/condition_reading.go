@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type ConditionReading struct {
+	DocType     string                `json:"docType"`                                    // Document type discriminator for rich queries
+	Excursions  []*ConditionExcursion `json:"Excursions"`                                 // Storage ranges the reading falls outside of
+	Humidity    *float64              `json:"Humidity,omitempty" metadata:",optional"`    // Measured relative humidity, nil if not measured
+	ID          string                `json:"ID"`                                         // Unique reading ID
+	MeasuredAt  time.Time             `json:"MeasuredAt"`                                 // Time the sensor took the reading
+	RecordedAt  time.Time             `json:"RecordedAt"`                                 // Ledger time the reading was recorded
+	RecordedBy  string                `json:"RecordedBy"`                                 // ID of the recording organization
+	SensorID    string                `json:"SensorID"`                                   // Identifier of the data logger
+	Temperature *float64              `json:"Temperature,omitempty" metadata:",optional"` // Measured temperature, nil if not measured
+	TransferID  string                `json:"TransferID"`                                 // Reference to Transfer.ID
+}
+
+type ConditionExcursion struct {
+	BatchID   string  `json:"BatchID"`   // Reference to Batch.ID whose range was breached
+	Condition string  `json:"Condition"` // Temperature or Humidity
+	Max       float64 `json:"Max"`       // Highest allowed value
+	Min       float64 `json:"Min"`       // Lowest allowed value
+	Value     float64 `json:"Value"`     // Measured value
+}
@@ -0,0 +1,32 @@
+package model
+
+const (
+	ContainerLevelCase   = "Case"
+	ContainerLevelPallet = "Pallet"
+)
+
+// Container is a case or pallet identified by its SSCC. Cases hold drugs and
+// pallets hold cases; a transfer that lists a container moves everything in it.
+type Container struct {
+	DocType       string `json:"docType"`       // Document type discriminator for rich queries
+	ID            string `json:"ID"`            // GS1 SSCC (AI 00) of the container
+	IsTransferred bool   `json:"isTransferred"` // Indicates if the container is in a pending transfer
+	Level         string `json:"Level"`         // Packaging level (Case, Pallet)
+	OwnerID       string `json:"OwnerID"`       // Current owner
+	ParentID      string `json:"ParentID"`      // SSCC of the enclosing container, empty at the top level
+}
+
+// ContainerContents is a container tree flattened into every nested container,
+// the root first, and every drug packed anywhere inside it.
+type ContainerContents struct {
+	Containers []*Container `json:"Containers"` // The root container followed by all nested containers
+	Drugs      []*Drug      `json:"Drugs"`      // Drugs packed at any level of the tree
+}
+
+func IsValidContainerLevel(level string) bool {
+	switch level {
+	case ContainerLevelCase, ContainerLevelPallet:
+		return true
+	}
+	return false
+}
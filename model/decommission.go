@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+const (
+	DecommissionKindDestroyed = "Destroyed"
+	DecommissionKindSample    = "Sample"
+	DecommissionKindStolen    = "Stolen"
+)
+
+const (
+	DecommissionStatusPending   = "Pending"
+	DecommissionStatusConfirmed = "Confirmed"
+	DecommissionStatusRejected  = "Rejected"
+	DecommissionStatusCancelled = "Cancelled"
+)
+
+const (
+	DecommissionReasonExpired           = "Expired"
+	DecommissionReasonDamaged           = "Damaged"
+	DecommissionReasonRecalled          = "Recalled"
+	DecommissionReasonNonSaleableReturn = "NonSaleableReturn"
+	DecommissionReasonQualityTesting    = "QualityTesting"
+	DecommissionReasonTheft             = "Theft"
+	DecommissionReasonOther             = "Other"
+)
+
+// Decommission records drugs permanently taken out of the supply chain. The
+// owner requests it and the witness confirms it; only then do the drugs leave
+// their owner's stock. They keep their ledger history either way.
+type Decommission struct {
+	CertificateHash  string    `json:"CertificateHash"`  // SHA-256 of the certificate of destruction, empty if none
+	DecommissionedAt time.Time `json:"DecommissionedAt"` // Ledger time the witness confirmed, zero until confirmed
+	DocType          string    `json:"docType"`          // Document type discriminator for rich queries
+	DrugsID          []string  `json:"DrugsID"`          // IDs of the decommissioned drugs
+	ID               string    `json:"ID"`               // Unique decommission ID
+	Kind             string    `json:"Kind"`             // Decommission kind (Destroyed, Sample, Stolen)
+	OwnerID          string    `json:"OwnerID"`          // ID of the organization that held the drugs
+	ReasonCode       string    `json:"ReasonCode"`       // Decommission reason code
+	RequestedAt      time.Time `json:"RequestedAt"`      // Ledger time the owner requested decommissioning
+	Status           string    `json:"Status"`           // Decommission status (Pending, Confirmed, Rejected, Cancelled)
+	WitnessID        string    `json:"WitnessID"`        // ID of the organization that must confirm the decommissioning
+}
+
+func IsValidDecommissionKind(kind string) bool {
+	switch kind {
+	case DecommissionKindDestroyed, DecommissionKindSample, DecommissionKindStolen:
+		return true
+	}
+	return false
+}
+
+func IsValidDecommissionReason(reasonCode string) bool {
+	switch reasonCode {
+	case DecommissionReasonExpired, DecommissionReasonDamaged, DecommissionReasonRecalled, DecommissionReasonNonSaleableReturn,
+		DecommissionReasonQualityTesting, DecommissionReasonTheft, DecommissionReasonOther:
+		return true
+	}
+	return false
+}
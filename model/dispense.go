@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Dispense records a pharmacy handing drugs to a patient. Dispensed drugs have
+// reached the end of the supply chain and can no longer be transferred.
+type Dispense struct {
+	DispensedAt    time.Time `json:"DispensedAt"`    // Ledger time of dispensing
+	DocType        string    `json:"docType"`        // Document type discriminator for rich queries
+	DrugsID        []string  `json:"DrugsID"`        // IDs of the dispensed drugs
+	ID             string    `json:"ID"`             // Unique dispense ID
+	PatientRef     string    `json:"PatientRef"`     // Pseudonymous patient reference, empty if not recorded
+	PharmacyID     string    `json:"PharmacyID"`     // ID of the dispensing pharmacy
+	PrescriptionID string    `json:"PrescriptionID"` // Prescription ID, empty if not recorded
+}
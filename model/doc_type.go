@@ -0,0 +1,15 @@
+package model
+
+const (
+	DocTypeBatch            = "batch"
+	DocTypeConditionReading = "conditionReading"
+	DocTypeContainer        = "container"
+	DocTypeDecommission     = "decommission"
+	DocTypeDispense         = "dispense"
+	DocTypeDrug             = "drug"
+	DocTypeHold             = "hold"
+	DocTypeOrganization     = "organization"
+	DocTypeProduct          = "product"
+	DocTypeScan             = "scan"
+	DocTypeTransfer         = "transfer"
+)
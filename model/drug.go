@@ -1,10 +1,22 @@
 package model
 
 type Drug struct {
-	BatchID       string `json:"BatchID"`       // Reference to Batch.ID
-	ID            string `json:"ID"`            // Unique drug ID
-	IsTransferred bool   `json:"isTransferred"` // Indicates if the drug has been transferred
-	Location      string `json:"Location"`      // Current location of the drug
-	OwnerID       string `json:"OwnerID"`       // Current owner
-	TransferID    string `json:"TransferID"`    // ID of the transfer transaction
+	Alerts           []string `json:"Alerts,omitempty" metadata:",optional"` // Scan alert codes raised against the drug
+	BatchID          string   `json:"BatchID"`                               // Reference to Batch.ID
+	ContainerID      string   `json:"ContainerID"`                           // SSCC of the case the drug is packed in, empty if loose
+	DecommissionID   string   `json:"DecommissionID"`                        // Reference to Decommission.ID, set while a decommission is pending or confirmed
+	DispenseID       string   `json:"DispenseID"`                            // Reference to Dispense.ID, empty until dispensed
+	DocType          string   `json:"docType"`                               // Document type discriminator for rich queries
+	GTIN             string   `json:"GTIN"`                                  // GS1 GTIN of the pack, empty for unserialized drugs
+	ID               string   `json:"ID"`                                    // Unique drug ID
+	IsDecommissioned bool     `json:"isDecommissioned"`                      // Indicates if the drug was destroyed, used as a sample or stolen
+	IsDispensed      bool     `json:"isDispensed"`                           // Indicates if the drug has been dispensed to a patient
+	IsFlagged        bool     `json:"isFlagged"`                             // Indicates if a scan raised an alert against the drug
+	IsNonSaleable    bool     `json:"isNonSaleable"`                         // Indicates if the drug came back in a non-saleable return
+	IsRecalled       bool     `json:"isRecalled"`                            // Indicates if the drug's batch has been recalled
+	IsTransferred    bool     `json:"isTransferred"`                         // Indicates if the drug has been transferred
+	Location         string   `json:"Location"`                              // Current location of the drug
+	OwnerID          string   `json:"OwnerID"`                               // Current owner
+	SerialNumber     string   `json:"SerialNumber"`                          // GS1 serial number (AI 21), unique per GTIN
+	TransferID       string   `json:"TransferID"`                            // ID of the transfer transaction
 }
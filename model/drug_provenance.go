@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+const (
+	CustodyEventManufactured = "Manufactured"
+	CustodyEventTransferred  = "Transferred"
+	CustodyEventDispensed    = "Dispensed"
+)
+
+type DrugProvenance struct {
+	Batch  *Batch        `json:"Batch"`  // Batch the drug was manufactured in
+	DrugID string        `json:"DrugID"` // Reference to Drug.ID
+	Hops   []*CustodyHop `json:"Hops"`   // Custody hops ordered from manufacturing to the current owner or patient
+}
+
+type CustodyHop struct {
+	Event            string    `json:"Event"`            // Manufactured, Transferred or Dispensed
+	ReceiveDate      time.Time `json:"ReceiveDate"`      // Date the receiver took custody
+	ReceiverID       string    `json:"ReceiverID"`       // Receiver ID, empty for the dispensing hop
+	ReceiverLocation string    `json:"ReceiverLocation"` // Receiver location, empty for the dispensing hop
+	SenderID         string    `json:"SenderID"`         // Sender ID, empty for the manufacturing hop
+	SenderLocation   string    `json:"SenderLocation"`   // Sender location, empty for the manufacturing hop
+	TransferDate     time.Time `json:"TransferDate"`     // Date the sender shipped the drug
+	TransferID       string    `json:"TransferID"`       // Reference to Transfer.ID, or to Dispense.ID for the dispensing hop
+}
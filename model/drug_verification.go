@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	VerdictGenuine            = "Genuine"
+	VerdictUnknown            = "Unknown"
+	VerdictRecalled           = "Recalled"
+	VerdictExpired            = "Expired"
+	VerdictDispensed          = "Dispensed"
+	VerdictDecommissioned     = "Decommissioned"
+	VerdictSuspectedDuplicate = "SuspectedDuplicate"
+)
+
+type DrugVerification struct {
+	DrugName         string    `json:"DrugName"`         // Drug name from the parent batch
+	ExpiryDate       time.Time `json:"ExpiryDate"`       // Expiry date from the parent batch
+	GTIN             string    `json:"GTIN"`             // GS1 GTIN of the pack, empty if not serialized
+	LotNumber        string    `json:"LotNumber"`        // Lot number printed on the pack
+	ManufacturerName string    `json:"ManufacturerName"` // Manufacturer name printed on the pack
+	Reason           string    `json:"Reason"`           // Explanation of a non-genuine verdict
+	SerialNumber     string    `json:"SerialNumber"`     // GS1 serial number of the pack
+	Verdict          string    `json:"Verdict"`          // Genuine, Unknown, Recalled, Expired, Dispensed, Decommissioned or SuspectedDuplicate
+	VerifiedAt       time.Time `json:"VerifiedAt"`       // Ledger time of the verification
+}
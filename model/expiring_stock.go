@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+const (
+	ExpiryHorizonExpired = "Expired"
+	ExpiryHorizon7Days   = "Within7Days"
+	ExpiryHorizon30Days  = "Within30Days"
+	ExpiryHorizon90Days  = "Within90Days"
+	ExpiryHorizonLater   = "Later"
+)
+
+// ExpiringStock groups the units of one batch held by an organization.
+type ExpiringStock struct {
+	BatchID      string    `json:"BatchID"`      // Reference to Batch.ID
+	DaysToExpiry int       `json:"DaysToExpiry"` // Whole days left until expiry, rounded down
+	DrugName     string    `json:"DrugName"`     // Drug name from the batch
+	DrugsID      []string  `json:"DrugsID"`      // IDs of the held drugs from the batch
+	ExpiryDate   time.Time `json:"ExpiryDate"`   // Expiry date of the batch
+	Horizon      string    `json:"Horizon"`      // Expiry horizon (Expired, Within7Days, Within30Days, Within90Days, Later)
+	LotNumber    string    `json:"LotNumber"`    // Lot number of the batch
+}
+
+// ExpiryHorizon buckets stock that has not expired yet by the whole days left.
+// Stock that is expired is labelled ExpiryHorizonExpired by the caller, because
+// rounding down gives 0 days both just before and at the moment of expiry.
+func ExpiryHorizon(daysToExpiry int) string {
+	switch {
+	case daysToExpiry < 7:
+		return ExpiryHorizon7Days
+	case daysToExpiry < 30:
+		return ExpiryHorizon30Days
+	case daysToExpiry < 90:
+		return ExpiryHorizon90Days
+	}
+	return ExpiryHorizonLater
+}
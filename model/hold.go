@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+const (
+	HoldScopeDrug  = "Drug"
+	HoldScopeBatch = "Batch"
+	HoldScopeOwner = "Owner"
+)
+
+// Hold quarantines a drug, every drug of a batch, or all stock of one owner until
+// it is released. Held drugs cannot be transferred.
+type Hold struct {
+	DocType    string    `json:"docType"`    // Document type discriminator for rich queries
+	ID         string    `json:"ID"`         // Unique hold ID
+	IsActive   bool      `json:"isActive"`   // Indicates if the hold is still in force
+	IssuedAt   time.Time `json:"IssuedAt"`   // Ledger time the hold was placed
+	IssuerID   string    `json:"IssuerID"`   // ID of the organization that placed the hold
+	Reason     string    `json:"Reason"`     // Reason given for the hold
+	ReleasedAt time.Time `json:"ReleasedAt"` // Ledger time the hold was released
+	ReleasedBy string    `json:"ReleasedBy"` // ID of the organization that released the hold
+	Scope      string    `json:"Scope"`      // Hold scope (Drug, Batch, Owner)
+	TargetID   string    `json:"TargetID"`   // Drug, batch or organization ID the hold applies to
+}
+
+func IsValidHoldScope(scope string) bool {
+	switch scope {
+	case HoldScopeDrug, HoldScopeBatch, HoldScopeOwner:
+		return true
+	}
+	return false
+}
@@ -1,8 +1,31 @@
 package model
 
+const (
+	OrgTypeManufacturer = "Manufacturer"
+	OrgTypeDistributor  = "Distributor"
+	OrgTypePharmacy     = "Pharmacy"
+	OrgTypePatient      = "Patient"
+	OrgTypeRegulator    = "Regulator"
+)
+
+const (
+	OrgStatusActive    = "Active"
+	OrgStatusSuspended = "Suspended"
+)
+
 type Organization struct {
+	DocType  string `json:"docType"`  // Document type discriminator for rich queries
 	ID       string `json:"ID"`       // Unique organization ID
 	Location string `json:"Location"` // Organization location
 	Name     string `json:"Name"`     // Organization name
+	Status   string `json:"Status"`   // Organization status (Active, Suspended)
 	Type     string `json:"Type"`     // Organization type (e.g., Manufacturer, Distributor, Pharmacy)
 }
+
+func IsValidOrgType(orgType string) bool {
+	switch orgType {
+	case OrgTypeManufacturer, OrgTypeDistributor, OrgTypePharmacy, OrgTypePatient, OrgTypeRegulator:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package model
+
+type PaginatedDrugs struct {
+	Bookmark     string  `json:"Bookmark"`     // Bookmark to request the next page, empty on the last page
+	FetchedCount int32   `json:"FetchedCount"` // Number of records fetched in this page
+	Records      []*Drug `json:"Records"`      // Drugs in this page
+}
+
+type PaginatedTransfers struct {
+	Bookmark     string      `json:"Bookmark"`     // Bookmark to request the next page, empty on the last page
+	FetchedCount int32       `json:"FetchedCount"` // Number of records fetched in this page
+	Records      []*Transfer `json:"Records"`      // Transfers in this page
+}
+
+type PaginatedBatches struct {
+	Bookmark     string   `json:"Bookmark"`     // Bookmark to request the next page, empty on the last page
+	FetchedCount int32    `json:"FetchedCount"` // Number of records fetched in this page
+	Records      []*Batch `json:"Records"`      // Batches in this page
+}
@@ -0,0 +1,13 @@
+package model
+
+type Product struct {
+	DocType                      string `json:"docType"`                      // Document type discriminator for rich queries
+	DosageForm                   string `json:"DosageForm"`                   // Dosage form, e.g. tablet or solution for injection
+	GenericName                  string `json:"GenericName"`                  // International non-proprietary name
+	GTIN                         string `json:"GTIN"`                         // GS1 GTIN of the trade item
+	ID                           string `json:"ID"`                           // Unique product ID
+	MarketingAuthorizationNumber string `json:"MarketingAuthorizationNumber"` // Marketing authorization number issued by the regulator
+	OwnerID                      string `json:"OwnerID"`                      // ID of the brand owner organization
+	PackSize                     int    `json:"PackSize"`                     // Number of dosage units in one pack
+	Strength                     string `json:"Strength"`                     // Strength, e.g. 500 mg
+}
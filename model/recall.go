@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+const (
+	RecallSeverityClassI   = "Class I"
+	RecallSeverityClassII  = "Class II"
+	RecallSeverityClassIII = "Class III"
+)
+
+type RecallStatus struct {
+	BatchID        string    `json:"BatchID"`        // Reference to Batch.ID
+	IsRecalled     bool      `json:"isRecalled"`     // Indicates if the batch has been recalled
+	RecallDate     time.Time `json:"RecallDate"`     // Recall date
+	RecallReason   string    `json:"RecallReason"`   // Reason given for the recall
+	RecallSeverity string    `json:"RecallSeverity"` // Recall severity (Class I, Class II, Class III)
+	RecalledBy     string    `json:"RecalledBy"`     // ID of the organization that ordered the recall
+	RecalledUnits  int       `json:"RecalledUnits"`  // Number of drugs flagged as recalled
+	TotalUnits     int       `json:"TotalUnits"`     // Number of drugs in the batch
+}
+
+type RecallHolder struct {
+	DrugsID        []string `json:"DrugsID"`        // Recalled drugs held by the organization
+	InTransit      int      `json:"InTransit"`      // Number of held drugs currently in a pending transfer
+	OrganizationID string   `json:"OrganizationID"` // Holder organization ID
+}
+
+func IsValidRecallSeverity(severity string) bool {
+	switch severity {
+	case RecallSeverityClassI, RecallSeverityClassII, RecallSeverityClassIII:
+		return true
+	}
+	return false
+}
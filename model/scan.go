@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+const (
+	ScanAlertDistantScan     = "DISTANT_SCAN"
+	ScanAlertNotOwner        = "NOT_OWNER"
+	ScanAlertAfterDispensing = "AFTER_DISPENSING"
+	ScanAlertDecommissioned  = "DECOMMISSIONED"
+)
+
+type Scan struct {
+	Alerts    []string  `json:"Alerts"`                                   // Alert codes raised by this scan
+	DocType   string    `json:"docType"`                                  // Document type discriminator for rich queries
+	DrugID    string    `json:"DrugID"`                                   // Reference to Drug.ID
+	ID        string    `json:"ID"`                                       // Unique scan ID
+	Latitude  *float64  `json:"Latitude,omitempty" metadata:",optional"`  // Scan latitude in degrees, nil if unknown
+	Location  string    `json:"Location"`                                 // Free-text scan location
+	Longitude *float64  `json:"Longitude,omitempty" metadata:",optional"` // Scan longitude in degrees, nil if unknown
+	ScannedAt time.Time `json:"ScannedAt"`                                // Ledger time of the scan
+	ScannerID string    `json:"ScannerID"`                                // ID of the scanning organization
+}
@@ -0,0 +1,19 @@
+package model
+
+const (
+	ConditionHumidity    = "Humidity"
+	ConditionTemperature = "Temperature"
+)
+
+type ConditionRange struct {
+	Max float64 `json:"Max"` // Highest allowed value
+	Min float64 `json:"Min"` // Lowest allowed value
+}
+
+// StorageConditions are the ranges a batch must be kept in. Temperatures are in
+// degrees Celsius and humidity in percent relative humidity; a nil range means
+// the condition is not controlled.
+type StorageConditions struct {
+	Humidity    *ConditionRange `json:"Humidity,omitempty" metadata:",optional"`    // Allowed relative humidity
+	Temperature *ConditionRange `json:"Temperature,omitempty" metadata:",optional"` // Allowed temperature
+}
@@ -2,11 +2,28 @@ package model
 
 import "time"
 
+const (
+	TransferStatusPending           = "Pending"
+	TransferStatusAccepted          = "Accepted"
+	TransferStatusPartiallyAccepted = "PartiallyAccepted"
+	TransferStatusRejected          = "Rejected"
+	TransferStatusCancelled         = "Cancelled"
+)
+
 type Transfer struct {
-	ID           string    `json:"ID"`           // Unique transfer ID
-	IsAccepted   bool      `json:"isAccepted"`   // null, true, false
-	ReceiveDate  time.Time `json:"ReceiveDate"`  // Receive date
-	ReceiverID   string    `json:"ReceiverID"`   // Receiver ID
-	SenderID     string    `json:"SenderID"`     // Sender ID
-	TransferDate time.Time `json:"TransferDate"` // Transfer date
+	ContainersID          []string               `json:"ContainersID,omitempty" metadata:",optional"`         // SSCCs of the top-level containers moved by the transfer
+	DeclaredReceiveDate   *time.Time             `json:"DeclaredReceiveDate,omitempty" metadata:",optional"`  // Receive date declared by the receiver, nil if not declared
+	DeclaredTransferDate  *time.Time             `json:"DeclaredTransferDate,omitempty" metadata:",optional"` // Transfer date declared by the sender, nil if not declared
+	DocType               string                 `json:"docType"`                                             // Document type discriminator for rich queries
+	DrugOutcomes          []*TransferDrugOutcome `json:"DrugOutcomes,omitempty" metadata:",optional"`         // Per-drug outcome of a partial acceptance
+	ExcursionBatchesID    []string               `json:"ExcursionBatchesID,omitempty" metadata:",optional"`   // Batches whose storage ranges were breached in transit
+	HasExcursion          bool                   `json:"hasExcursion"`                                        // Indicates if a condition reading breached a storage range
+	ID                    string                 `json:"ID"`                                                  // Unique transfer ID
+	IsExcursionOverridden bool                   `json:"isExcursionOverridden"`                               // Indicates if the receiver accepted excursion units by override
+	ReceiveDate           time.Time              `json:"ReceiveDate"`                                         // Ledger time the receiver processed the transfer
+	ReceiverID            string                 `json:"ReceiverID"`                                          // Receiver ID
+	Return                *TransferReturn        `json:"Return,omitempty" metadata:",optional"`               // Return details, nil for forward transfers
+	SenderID              string                 `json:"SenderID"`                                            // Sender ID
+	Status                string                 `json:"Status"`                                              // Transfer status (Pending, Accepted, PartiallyAccepted, Rejected, Cancelled)
+	TransferDate          time.Time              `json:"TransferDate"`                                        // Ledger time the transfer was created
 }
@@ -0,0 +1,28 @@
+package model
+
+const (
+	DrugOutcomeReceived = "Received"
+	DrugOutcomeRefused  = "Refused"
+)
+
+const (
+	RefusalReasonDamaged      = "Damaged"
+	RefusalReasonExcursion    = "ConditionExcursion"
+	RefusalReasonMissing      = "Missing"
+	RefusalReasonWrongProduct = "WrongProduct"
+	RefusalReasonOther        = "Other"
+)
+
+type TransferDrugOutcome struct {
+	DrugID     string `json:"DrugID"`     // Reference to Drug.ID
+	Outcome    string `json:"Outcome"`    // Received or Refused
+	ReasonCode string `json:"ReasonCode"` // Refusal reason code, empty for received drugs
+}
+
+func IsValidRefusalReason(reasonCode string) bool {
+	switch reasonCode {
+	case RefusalReasonDamaged, RefusalReasonExcursion, RefusalReasonMissing, RefusalReasonWrongProduct, RefusalReasonOther:
+		return true
+	}
+	return false
+}
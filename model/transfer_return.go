@@ -0,0 +1,39 @@
+package model
+
+const (
+	ReturnReasonNearExpiry   = "NearExpiry"
+	ReturnReasonDamaged      = "Damaged"
+	ReturnReasonRecalled     = "Recalled"
+	ReturnReasonOverstock    = "Overstock"
+	ReturnReasonWrongProduct = "WrongProduct"
+	ReturnReasonOther        = "Other"
+)
+
+const (
+	ReturnDispositionSaleable    = "Saleable"
+	ReturnDispositionNonSaleable = "NonSaleable"
+)
+
+// TransferReturn marks a transfer as a return of stock to the partner that
+// supplied it in the original forward transfer.
+type TransferReturn struct {
+	Disposition        string `json:"Disposition"`        // Whether the returned units can be resold (Saleable, NonSaleable)
+	OriginalTransferID string `json:"OriginalTransferID"` // Reference to the forward Transfer.ID the units arrived in
+	ReasonCode         string `json:"ReasonCode"`         // Return reason code
+}
+
+func IsValidReturnReason(reasonCode string) bool {
+	switch reasonCode {
+	case ReturnReasonNearExpiry, ReturnReasonDamaged, ReturnReasonRecalled, ReturnReasonOverstock, ReturnReasonWrongProduct, ReturnReasonOther:
+		return true
+	}
+	return false
+}
+
+func IsValidReturnDisposition(disposition string) bool {
+	switch disposition {
+	case ReturnDispositionSaleable, ReturnDispositionNonSaleable:
+		return true
+	}
+	return false
+}
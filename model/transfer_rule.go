@@ -0,0 +1,48 @@
+package model
+
+import "encoding/json"
+
+const (
+	ViolationReceiverMissing   = "RECEIVER_MISSING"
+	ViolationReceiverNotFound  = "RECEIVER_NOT_FOUND"
+	ViolationReceiverSuspended = "RECEIVER_SUSPENDED"
+	ViolationSelfTransfer      = "SELF_TRANSFER"
+	ViolationRouteNotAllowed   = "ROUTE_NOT_ALLOWED"
+	ViolationReturnRouteOnly   = "RETURN_ROUTE_ONLY"
+)
+
+type TransferRule struct {
+	IsReturn     bool   `json:"isReturn"`     // Indicates if the route sends stock back upstream
+	ReceiverType string `json:"ReceiverType"` // Organization type allowed to receive
+	SenderType   string `json:"SenderType"`   // Organization type allowed to send
+}
+
+// TransferRuleViolation is returned by CreateTransfer when a transfer breaks the
+// configured transfer rules. Its Error string is the JSON encoding of the struct so
+// clients can decode the violation from the transaction error message.
+type TransferRuleViolation struct {
+	Code         string `json:"Code"`         // Violation code
+	Message      string `json:"Message"`      // Human readable description
+	ReceiverID   string `json:"ReceiverID"`   // Receiver ID
+	ReceiverType string `json:"ReceiverType"` // Receiver organization type, empty if unknown
+	SenderID     string `json:"SenderID"`     // Sender ID
+	SenderType   string `json:"SenderType"`   // Sender organization type
+}
+
+func (v *TransferRuleViolation) Error() string {
+	violationJSON, err := json.Marshal(v)
+	if err != nil {
+		return v.Message
+	}
+	return string(violationJSON)
+}
+
+func DefaultTransferRules() []*TransferRule {
+	return []*TransferRule{
+		{SenderType: OrgTypeManufacturer, ReceiverType: OrgTypeDistributor},
+		{SenderType: OrgTypeDistributor, ReceiverType: OrgTypePharmacy},
+		{SenderType: OrgTypePharmacy, ReceiverType: OrgTypePatient},
+		{SenderType: OrgTypeDistributor, ReceiverType: OrgTypeManufacturer, IsReturn: true},
+		{SenderType: OrgTypePharmacy, ReceiverType: OrgTypeDistributor, IsReturn: true},
+	}
+}